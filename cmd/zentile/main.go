@@ -5,11 +5,15 @@ import (
 	"github.com/Alnivel/zentile/internal/cli"
 	"github.com/Alnivel/zentile/internal/config"
 	"github.com/Alnivel/zentile/internal/daemon"
+	"github.com/Alnivel/zentile/internal/socket"
 	log "github.com/sirupsen/logrus"
 )
 
 type Flags struct {
 	verbose bool
+	json    bool
+	script  string
+	socket  string
 }
 
 type Args []string
@@ -17,8 +21,15 @@ type Args []string
 func parseArgs() (Args, Flags) {
 	flags := Flags{}
 	flag.BoolVar(&flags.verbose, "v", false, "verbose mode")
+	flag.BoolVar(&flags.json, "json", false, "print results of commands as JSON lines")
+	flag.StringVar(&flags.script, "f", "", "run command sequences from the script file, one per line")
+	flag.StringVar(&flags.socket, "socket", "", "path of the daemon socket (default $"+socket.PATH_ENV+" or $XDG_RUNTIME_DIR/zentile/$DISPLAY.sock)")
 	flag.Parse()
 
+	if flags.socket == "" {
+		flags.socket = socket.DefaultPath()
+	}
+
 	return flag.Args(), flags
 }
 
@@ -31,12 +42,26 @@ func main() {
 	}
 	setLogLevel(flags.verbose)
 
+	cliOptions := cli.Options{JSON: flags.json, SocketPath: flags.socket, MaxMessageLength: config.MaxMessageLength}
+
 	runAsDaemon := len(args) == 0
-	if runAsDaemon {
-		daemon.Start(config, args)
-	} else {
+	switch {
+	case flags.script != "":
+		cli.RunScript(config, flags.script, cliOptions)
+	case runAsDaemon:
+		daemon.Start(config, flags.socket)
+	case args[0] == cli.STDIN_SCRIPT:
+		cli.RunScript(config, cli.STDIN_SCRIPT, cliOptions)
+	case args[0] == "subscribe":
+		cli.Subscribe(config, args[1:], cliOptions)
+	case args[0] == "completion":
+		cli.Completion(args[1:])
+	case args[0] == "__complete":
+		// Used by the completion scripts
+		cli.Complete(config, args[1:], cliOptions)
+	default:
 		// sending command
-		cli.Run(config, args)
+		cli.Run(config, args, cliOptions)
 	}
 
 }
@@ -8,18 +8,42 @@ import (
 	"github.com/Alnivel/zentile/internal/command_parser"
 	"github.com/Alnivel/zentile/internal/config"
 	"github.com/Alnivel/zentile/internal/daemon"
+	"github.com/Alnivel/zentile/internal/protocol"
 	"github.com/Alnivel/zentile/internal/types"
 	log "github.com/sirupsen/logrus"
 )
 
 const (
-	OK            = 0
-	COMMAND_ERROR = 1
-	PARSE_ERROR   = 2
-	SOCKET_ERROR  = 3
+	OK                           = 0
+	COMMAND_ERROR                = 1 // Command failed with error of no other category
+	PARSE_ERROR                  = 2
+	SOCKET_ERROR                 = 3
+	UNKNOWN_COMMAND_ERROR        = 4
+	BAD_ARGUMENTS_ERROR          = 5
+	WINDOW_NOT_FOUND_ERROR       = 6
+	WORKSPACE_OUT_OF_RANGE_ERROR = 7
+	NOT_TILING_ERROR             = 8
+	BACKEND_ERROR                = 9
 )
 
-func Run(config config.Config, args []string) {
+// Status codes of the commands failed with the error codes,
+// the codes not listed are reported as COMMAND_ERROR
+var errorCodeStatuses = map[protocol.ErrorCode]int{
+	protocol.CodeUnknownCommand:      UNKNOWN_COMMAND_ERROR,
+	protocol.CodeBadArguments:        BAD_ARGUMENTS_ERROR,
+	protocol.CodeWindowNotFound:      WINDOW_NOT_FOUND_ERROR,
+	protocol.CodeWorkspaceOutOfRange: WORKSPACE_OUT_OF_RANGE_ERROR,
+	protocol.CodeNotTiling:           NOT_TILING_ERROR,
+	protocol.CodeBackendFailure:      BACKEND_ERROR,
+}
+
+type Options struct {
+	JSON             bool   // Print results as JSON lines
+	SocketPath       string // Socket of the daemon
+	MaxMessageLength int    // Limit of the replies received from the daemon in bytes
+}
+
+func Run(config config.Config, args []string, options Options) {
 	statusCode := OK
 	commandsStatusCode := OK
 
@@ -30,7 +54,7 @@ func Run(config config.Config, args []string) {
 		os.Exit(statusCode)
 	}()
 
-	commands := daemon.InitCommands(nil, nil)
+	commands := daemon.InitCommands(nil, nil, &config)
 	getCommandByNameAdapter := func(kind types.CommandType, name string) (commandparser.CommandWrap, bool) { 
 		return commands.GetByName(kind, name) 
 	}
@@ -50,14 +74,28 @@ func Run(config config.Config, args []string) {
 		socketCommands[i] = types.Command(v)
 	}
 
-	socketPath := "/tmp/zentile.sock"
-	resultChan, err := sendCommands(socketPath, socketCommands)
+	format := "text"
+	if options.JSON {
+		format = "json"
+	}
+
+	resultChan, err := sendCommands(options, socketCommands, format)
 	if err != nil {
 		log.Error(err.Error())
 		statusCode = SOCKET_ERROR
 		return
 	}
 
+	statusCode, commandsStatusCode = printResults(resultChan, options)
+}
+
+// Print results of the commands as they come.
+// Returns status code of the connection and status code of the commands,
+// which is determined by the first failed command.
+func printResults(resultChan <-chan CommandResult, options Options) (int, int) {
+	statusCode := OK
+	commandsStatusCode := OK
+
 	for r := range resultChan {
 		const logFormat = "\n\t> %s\n\t< %s\n"
 
@@ -66,16 +104,31 @@ func Run(config config.Config, args []string) {
 			statusCode = SOCKET_ERROR
 			log.Errorf(logFormat, r.command, r.err)
 		case r.reply.Kind == "ERR":
-			commandsStatusCode = COMMAND_ERROR
+			if commandsStatusCode == OK {
+				code, _ := protocol.ReplyError(*r.reply)
+				commandsStatusCode = errorCodeStatus(code)
+			}
 			log.Errorf(logFormat, r.command, r.reply)
 		default:
 			log.Debugf(logFormat, r.command, r.reply)
 
-			if len(r.reply.Args) > 0 {
+			if len(r.reply.Args) > 0 && !options.JSON {
 				formatedResultArgs := strings.Join(r.reply.Args, ", ")
 				fmt.Println(formatedResultArgs)
 			}
 		}
 
+		if options.JSON {
+			printResultJSON(r)
+		}
+	}
+
+	return statusCode, commandsStatusCode
+}
+
+func errorCodeStatus(code protocol.ErrorCode) int {
+	if status, exists := errorCodeStatuses[code]; exists {
+		return status
 	}
+	return COMMAND_ERROR
 }
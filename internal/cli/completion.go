@@ -0,0 +1,265 @@
+package cli
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+
+	commandparser "github.com/Alnivel/zentile/internal/command_parser"
+	"github.com/Alnivel/zentile/internal/config"
+	"github.com/Alnivel/zentile/internal/daemon"
+	"github.com/Alnivel/zentile/internal/protocol"
+	"github.com/Alnivel/zentile/internal/types"
+	log "github.com/sirupsen/logrus"
+)
+
+// The scripts ask zentile itself for candidates, so they are always in sync
+// with the commands of the daemon, including aliases from the config
+var completionScripts = map[string]string{
+	"bash": `# bash completion for zentile
+_zentile() {
+	# Selectors such as class:CLASS and title~=REGEX are single words for zentile
+	local cur words cword
+	_get_comp_words_by_ref -n =: cur words cword
+
+	mapfile -t COMPREPLY < <(zentile __complete "${words[@]:1:cword}" 2>/dev/null)
+
+	# Bash replaces only the part of the word after the last : or = in COMP_WORDBREAKS
+	local breaks=${COMP_WORDBREAKS//[^:=]/}
+	if [[ -n $breaks ]]; then
+		local prefix=${cur%"${cur##*[$breaks]}"}
+		COMPREPLY=("${COMPREPLY[@]#"$prefix"}")
+	fi
+}
+complete -F _zentile zentile
+`,
+	"zsh": `#compdef zentile
+# zsh completion for zentile
+_zentile() {
+	local -a candidates
+	candidates=("${(@f)$(zentile __complete "${(@)words[2,CURRENT]}" 2>/dev/null)}")
+	compadd -- $candidates
+}
+if [ "$funcstack[1]" = "_zentile" ]; then
+	_zentile "$@"
+else
+	compdef _zentile zentile
+fi
+`,
+	"fish": `# fish completion for zentile
+function __zentile_complete
+	set -l words (commandline -opc) (commandline -ct)
+	zentile __complete $words[2..-1] 2>/dev/null
+end
+complete -c zentile -f -a '(__zentile_complete)'
+`,
+}
+
+// Subcommands of the CLI besides the commands of the daemon
+var subcommands = []string{"subscribe", "completion"}
+
+// Completion prints completion script for the shell
+func Completion(args []string) {
+	shells := slices.Sorted(maps.Keys(completionScripts))
+
+	if len(args) != 1 {
+		log.Errorf("Expected exactly one shell name, one of: %v", strings.Join(shells, ", "))
+		os.Exit(PARSE_ERROR)
+	}
+
+	script, exists := completionScripts[args[0]]
+	if !exists {
+		log.Errorf("Unknown shell %v, expected one of: %v", args[0], strings.Join(shells, ", "))
+		os.Exit(PARSE_ERROR)
+	}
+
+	fmt.Print(script)
+}
+
+// Complete prints candidates for the last of the words,
+// the words are the command line without the program name
+func Complete(config config.Config, words []string, options Options) {
+	if len(words) == 0 {
+		return
+	}
+
+	commands := daemon.InitCommands(nil, nil, &config)
+	current := words[len(words)-1]
+	previous, socketPath := skipFlags(words[:len(words)-1])
+	if socketPath != "" {
+		options.SocketPath = socketPath
+	}
+	for _, candidate := range completionCandidates(commands, previous, options) {
+		if strings.HasPrefix(candidate, current) {
+			fmt.Println(candidate)
+		}
+	}
+}
+
+// Flags of the CLI followed by a value
+var valueFlags = []string{"f", "socket"}
+
+// Skip the leading flags and their values.
+// Returns the rest of the words and the socket path if it is set by the flags.
+func skipFlags(words []string) ([]string, string) {
+	socketPath := ""
+	for len(words) > 0 && strings.HasPrefix(words[0], "-") && words[0] != STDIN_SCRIPT {
+		name, value, hasValue := strings.Cut(strings.TrimLeft(words[0], "-"), "=")
+		words = words[1:]
+
+		if !hasValue && slices.Contains(valueFlags, name) && len(words) > 0 {
+			value, words = words[0], words[1:]
+		}
+		if name == "socket" {
+			socketPath = value
+		}
+	}
+	return words, socketPath
+}
+
+func completionCandidates(commands daemon.Commands, previous []string, options Options) []string {
+	if len(previous) == 0 {
+		return append(commandStarters(commands), subcommands...)
+	}
+	switch previous[0] {
+	case "subscribe":
+		return eventNames()
+	case "completion":
+		if len(previous) == 1 {
+			return slices.Sorted(maps.Keys(completionScripts))
+		}
+		return nil
+	}
+
+	// Follow the parser to find out what the current word is
+	var command daemon.CommandWrap
+	var kind types.CommandType
+	inCommand, expectName := false, false
+	argNum := 0
+
+	for _, word := range previous {
+		endsCommand := strings.HasSuffix(word, commandparser.COMMAND_SEPARATOR)
+		word = strings.TrimSuffix(word, commandparser.COMMAND_SEPARATOR)
+
+		switch {
+		case word == "":
+		case word == commandparser.BLOCK_START || word == commandparser.BLOCK_END:
+			// Commands of a block are completed as usual
+			inCommand, expectName = false, false
+		case inCommand:
+			argNum++
+			inCommand = argNum < command.MaxIn()
+		case expectName:
+			expectName = false
+			command, inCommand = commands.GetByName(kind, word)
+			argNum = 0
+			inCommand = inCommand && command.MaxIn() > 0
+		case isKeyword(word):
+			kind = types.CommandType(strings.ToUpper(word))
+			expectName = true
+		default:
+			command, inCommand = commands.GetByName(types.Action, word)
+			argNum = 0
+			inCommand = inCommand && command.MaxIn() > 0
+		}
+
+		if endsCommand {
+			inCommand, expectName = false, false
+		}
+	}
+
+	switch {
+	case expectName:
+		return commandNames(commands.Map(kind))
+	case inCommand:
+		var candidates []string
+		if argNum < len(command.Args()) {
+			candidates = argCandidates(command.Args()[argNum], options)
+		}
+		if argNum >= command.MinIn() {
+			candidates = append(candidates, commandStarters(commands)...)
+		}
+		return candidates
+	default:
+		return commandStarters(commands)
+	}
+}
+
+var keywords = []types.CommandType{types.Set, types.Query, types.For, types.Foreach}
+
+func isKeyword(word string) bool {
+	return slices.Contains(keywords, types.CommandType(strings.ToUpper(word)))
+}
+
+// Words starting a new command: actions and keywords
+func commandStarters(commands daemon.Commands) []string {
+	starters := commandNames(commands.Actions)
+	for _, keyword := range keywords {
+		starters = append(starters, strings.ToLower(string(keyword)))
+	}
+	return starters
+}
+
+// Sorted names of the commands except internal ones
+func commandNames(commandMap daemon.CommandMap) []string {
+	names := make([]string, 0, len(commandMap))
+	for _, name := range slices.Sorted(maps.Keys(commandMap)) {
+		if !strings.HasPrefix(name, "__") {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+func argCandidates(arg types.ArgSpec, options Options) []string {
+	if len(arg.Values) > 0 {
+		return arg.Values
+	}
+
+	switch arg.Type {
+	case types.ArgWindow:
+		candidates := append([]string{"%target"}, daemon.WindowSelectors...)
+		for _, mark := range queryFirstFields("marks", options) {
+			candidates = append(candidates, "%"+mark)
+		}
+		return append(candidates, queryFirstFields("windows", options)...)
+	case types.ArgWorkspace:
+		return append(slices.Clone(daemon.WorkspaceTargets), queryFirstFields("workspaces", options)...)
+	case types.ArgMark:
+		return queryFirstFields("marks", options)
+	default:
+		return nil
+	}
+}
+
+// Ask the running daemon for the records and return their first fields,
+// nothing is returned if the daemon is not running
+func queryFirstFields(queryName string, options Options) []string {
+	query := types.Command{Kind: types.Query, Name: queryName, Args: []string{}}
+	resultChan, err := sendCommands(options, []types.Command{query}, "text")
+	if err != nil {
+		return nil
+	}
+
+	var fields []string
+	for r := range resultChan {
+		if r.err != nil || r.reply.Kind != "OK" {
+			continue
+		}
+		for _, record := range r.reply.Args {
+			field, _, _ := strings.Cut(record, types.RECORD_SEPARATOR)
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+func eventNames() []string {
+	names := make([]string, len(protocol.EventNames))
+	for i, name := range protocol.EventNames {
+		names[i] = string(name)
+	}
+	return names
+}
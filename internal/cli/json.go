@@ -0,0 +1,65 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/Alnivel/zentile/internal/protocol"
+	log "github.com/sirupsen/logrus"
+)
+
+type jsonCommand struct {
+	Kind string   `json:"kind"`
+	Name string   `json:"name"`
+	Args []string `json:"args"`
+}
+
+type jsonResult struct {
+	Command jsonCommand       `json:"command"`
+	Ok      bool              `json:"ok"`
+	Result  []json.RawMessage `json:"result"`
+	Error   string            `json:"error,omitempty"`
+	Code    string            `json:"code,omitempty"` // Code of the error
+}
+
+// Print result of the command as a single JSON line
+func printResultJSON(r CommandResult) {
+	output := jsonResult{
+		Command: jsonCommand{
+			Kind: r.command.Kind,
+			Args: []string{},
+		},
+	}
+	if len(r.command.Args) > 0 {
+		output.Command.Name = r.command.Args[0]
+		output.Command.Args = r.command.Args[1:]
+	}
+
+	switch {
+	case r.err != nil:
+		output.Error = r.err.Error()
+	case r.reply.Kind == "ERR":
+		code, text := protocol.ReplyError(*r.reply)
+		output.Code = string(code)
+		output.Error = text
+	default:
+		output.Ok = true
+		// The daemon sends each result as JSON value when asked for json format
+		output.Result = make([]json.RawMessage, 0, len(r.reply.Args))
+		for _, arg := range r.reply.Args {
+			if !json.Valid([]byte(arg)) {
+				// Should not happen, but do not break the output if it does
+				encodedArg, _ := json.Marshal(arg)
+				arg = string(encodedArg)
+			}
+			output.Result = append(output.Result, json.RawMessage(arg))
+		}
+	}
+
+	encoded, err := json.Marshal(output)
+	if err != nil {
+		log.Errorf("Failed to encode result as JSON: %v", err)
+		return
+	}
+	fmt.Println(string(encoded))
+}
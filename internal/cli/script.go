@@ -0,0 +1,154 @@
+package cli
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	commandparser "github.com/Alnivel/zentile/internal/command_parser"
+	"github.com/Alnivel/zentile/internal/config"
+	"github.com/Alnivel/zentile/internal/daemon"
+	"github.com/Alnivel/zentile/internal/socket"
+	"github.com/Alnivel/zentile/internal/types"
+	log "github.com/sirupsen/logrus"
+)
+
+// Path of the script meaning the standard input
+const STDIN_SCRIPT = "-"
+
+const (
+	SCRIPT_COMMENT      = "#"
+	SCRIPT_CONTINUATION = `\`
+)
+
+// Line of a script, possibly joined from several lines by continuation
+type scriptLine struct {
+	num  int // Number of the first joined line
+	text string
+}
+
+// RunScript runs the command sequences from the script file, or the standard input if path is "-".
+// Each line of the script is a separate command sequence, as if it was passed to the CLI,
+// but all of them are sent over one connection.
+// The script is parsed as a whole before running, so nothing is run if any line is invalid.
+func RunScript(config config.Config, path string, options Options) {
+	statusCode := OK
+	commandsStatusCode := OK
+
+	defer func() {
+		if statusCode == OK {
+			statusCode = commandsStatusCode
+		}
+		os.Exit(statusCode)
+	}()
+
+	var input io.Reader = os.Stdin
+	if path != STDIN_SCRIPT {
+		file, err := os.Open(path)
+		if err != nil {
+			log.Error(err.Error())
+			statusCode = PARSE_ERROR
+			return
+		}
+		defer file.Close()
+		input = file
+	}
+
+	lines, err := readScriptLines(input, options.MaxMessageLength)
+	if err != nil {
+		log.Errorf("%v:%v", path, err)
+		statusCode = PARSE_ERROR
+		return
+	}
+
+	commands := daemon.InitCommands(nil, nil, &config)
+	getCommandByNameAdapter := func(kind types.CommandType, name string) (commandparser.CommandWrap, bool) {
+		return commands.GetByName(kind, name)
+	}
+	parser := commandparser.CommandParser{
+		GetCommandByName: getCommandByNameAdapter,
+	}
+
+	sequences := make([][]types.Command, 0, len(lines))
+	for _, line := range lines {
+		sequence, err := parser.ParseString(line.text)
+		if err != nil {
+			log.Errorf("%v:%v: %v", path, line.num, err)
+			statusCode = PARSE_ERROR
+			continue
+		}
+		sequences = append(sequences, sequence)
+	}
+	if statusCode != OK {
+		return
+	}
+
+	format := "text"
+	if options.JSON {
+		format = "json"
+	}
+
+	resultChan, err := sendSequences(options, sequences, format)
+	if err != nil {
+		log.Error(err.Error())
+		statusCode = SOCKET_ERROR
+		return
+	}
+
+	statusCode, commandsStatusCode = printResults(resultChan, options)
+}
+
+// Read lines of the script skipping blank lines and comments,
+// lines ending with backslash are joined with the next line.
+// A line can be as long as a message sent to the daemon, maxLineLength bytes or the default limit if it is not positive.
+func readScriptLines(input io.Reader, maxLineLength int) ([]scriptLine, error) {
+	if maxLineLength <= 0 {
+		maxLineLength = socket.DEFAULT_MAX_MESSAGE_LENGTH
+	}
+
+	lines := make([]scriptLine, 0)
+	scanner := bufio.NewScanner(input)
+	scanner.Buffer(make([]byte, 0, bufio.MaxScanTokenSize), maxLineLength)
+
+	var current *scriptLine
+	num := 0
+	for scanner.Scan() {
+		num++
+		text := strings.TrimSpace(scanner.Text())
+
+		if current == nil {
+			if text == "" || strings.HasPrefix(text, SCRIPT_COMMENT) {
+				continue
+			}
+			current = &scriptLine{num: num}
+		}
+
+		continued := isContinued(text)
+		if continued {
+			text = strings.TrimSuffix(text, SCRIPT_CONTINUATION)
+		}
+		current.text = strings.TrimSpace(current.text + " " + text)
+
+		if !continued {
+			lines = append(lines, *current)
+			current = nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if current != nil {
+		return nil, fmt.Errorf("%v: Line continues past the end of the script", current.num)
+	}
+	return lines, nil
+}
+
+// The line is continued if it ends with an odd number of backslashes,
+// otherwise the last backslash is escaped and belongs to the command
+func isContinued(text string) bool {
+	trimmed := strings.TrimRight(text, SCRIPT_CONTINUATION)
+	return (len(text)-len(trimmed))%2 == 1
+}
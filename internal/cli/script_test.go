@@ -0,0 +1,101 @@
+package cli
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func Test_readScriptLines(t *testing.T) {
+	longLine := "set layout " + strings.Repeat("x", 100000)
+
+	tests := []struct {
+		name string
+
+		input   string
+		limit   int
+		want    []scriptLine
+		wantErr bool
+	}{
+		{
+			name:  "CommentsAndBlankLines",
+			input: "# comment\n\n  tile\n   \n  # indented comment\nuntile\n",
+			want:  []scriptLine{{3, "tile"}, {6, "untile"}},
+		},
+		{
+			name:  "Continuation",
+			input: "for window %master \\\n  make_active_window_master\ntile\n",
+			want:  []scriptLine{{1, "for window %master make_active_window_master"}, {3, "tile"}},
+		},
+		{
+			name:  "CommentInsideContinuation",
+			input: "tile \\\n# not a comment\n",
+			want:  []scriptLine{{1, "tile # not a comment"}},
+		},
+		{
+			name:  "EscapedBackslashAtEnd",
+			input: "mark a\\\\\ntile\n",
+			want:  []scriptLine{{1, "mark a\\\\"}, {2, "tile"}},
+		},
+		{
+			name:  "EscapedBackslashBeforeContinuation",
+			input: "mark a\\\\\\\ntile\n",
+			want:  []scriptLine{{1, "mark a\\\\ tile"}},
+		},
+		{
+			name:    "ContinuationAtEOF",
+			input:   "tile\nuntile \\\n",
+			wantErr: true,
+		},
+		{
+			name:  "NoNewlineAtEOF",
+			input: "tile",
+			want:  []scriptLine{{1, "tile"}},
+		},
+		{
+			name:  "LongerThanScannerDefault",
+			input: longLine + "\n",
+			limit: 200000,
+			want:  []scriptLine{{1, longLine}},
+		},
+		{
+			name:    "LongerThanLimit",
+			input:   longLine + "\n",
+			limit:   1000,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := readScriptLines(strings.NewReader(tt.input), tt.limit)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Got error %v, expecting error %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_isContinued(t *testing.T) {
+	tests := []struct {
+		text string
+		want bool
+	}{
+		{"tile", false},
+		{"", false},
+		{`tile \`, true},
+		{`mark a\\`, false},
+		{`mark a\\\`, true},
+		{`\`, true},
+		{`\\`, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			if got := isContinued(tt.text); got != tt.want {
+				t.Errorf("isContinued(%q) = %v, want %v", tt.text, got, tt.want)
+			}
+		})
+	}
+}
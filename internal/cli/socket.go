@@ -1,48 +1,153 @@
 package cli
 
 import (
+	"fmt"
+	"time"
+
+	"github.com/Alnivel/zentile/internal/protocol"
 	"github.com/Alnivel/zentile/internal/socket"
 	"github.com/Alnivel/zentile/internal/types"
 )
 
+// How long to wait for the reply to HELLO
+const handshakeTimeout = time.Second
+
 type CommandResult struct {
 	command *socket.Message
 	reply   *socket.Message
 	err     error
 }
 
-func sendCommands(socketPath string, commands []types.Command) (<-chan CommandResult, error) {
-	c, err := socket.Dial(socketPath)
+func sendCommands(options Options, commands []types.Command, format string) (<-chan CommandResult, error) {
+	return sendSequences(options, [][]types.Command{commands}, format)
+}
+
+// Send the command sequences over one connection,
+// each sequence starts with the default targets
+func sendSequences(options Options, sequences [][]types.Command, format string) (<-chan CommandResult, error) {
+	c, err := dialDaemon(options)
 	if err != nil {
 		return nil, err
 	}
 
+	if err := requestFormat(&c, format); err != nil {
+		c.Close()
+		return nil, err
+	}
+
 	resultChan := make(chan CommandResult)
 	go func() {
 		defer c.Close()
 		defer close(resultChan)
 
-		for _, command := range commands {
-			commandMessage := socket.Message{
-				Kind: string(command.Kind),
-				Args: append([]string{command.Name}, command.Args...),
+		for _, commands := range sequences {
+			beginMessage := socket.Message{Kind: "BEGIN", Args: []string{}}
+			if err := beginSequence(&c); err != nil {
+				resultChan <- CommandResult{&beginMessage, nil, err}
+				return
 			}
 
-			err := c.SendMessage(commandMessage)
-			if err != nil {
-				resultChan <- CommandResult{&commandMessage, nil, err}
-				return
+			for _, command := range commands {
+				commandMessage := socket.Message{
+					Kind: string(command.Kind),
+					Args: append([]string{command.Name}, command.Args...),
+				}
+
+				err := c.SendMessage(commandMessage)
+				if err != nil {
+					resultChan <- CommandResult{&commandMessage, nil, err}
+					return
+				}
+
+				replyMessage, err := c.Receive()
+				if err != nil {
+					resultChan <- CommandResult{&commandMessage, nil, err}
+					return
+				}
+
+				resultChan <- CommandResult{&commandMessage, &replyMessage, nil}
 			}
 
-			replyMessage, err := c.Receive()
+			// Release the daemon as soon as the sequence is done.
+			// The layout changes are applied then, so the reply is a result too if they fail.
+			endMessage := socket.Message{Kind: "END", Args: []string{}}
+			endReply, err := endSequence(&c)
 			if err != nil {
-				resultChan <- CommandResult{&commandMessage, nil, err}
+				resultChan <- CommandResult{&endMessage, nil, err}
 				return
 			}
-
-			resultChan <- CommandResult{&commandMessage, &replyMessage, nil}
+			if endReply.Kind != "OK" {
+				resultChan <- CommandResult{&endMessage, &endReply, nil}
+			}
 		}
 	}()
 
 	return resultChan, nil
 }
+
+// Connect to the daemon and check that it speaks the same protocol version
+func dialDaemon(options Options) (socket.Conn, error) {
+	c, err := socket.Dial(options.SocketPath, options.MaxMessageLength)
+	if err != nil {
+		return c, err
+	}
+
+	c.SetReadDeadline(time.Now().Add(handshakeTimeout))
+	err = protocol.Handshake(&c)
+	c.SetReadDeadline(time.Time{})
+	if err != nil {
+		c.Close()
+		return c, err
+	}
+	return c, nil
+}
+
+// Ask the daemon to start a new command sequence, resetting the targets
+func beginSequence(c *socket.Conn) error {
+	err := c.Send("BEGIN")
+	if err != nil {
+		return err
+	}
+
+	reply, err := c.Receive()
+	if err != nil {
+		return err
+	}
+	if reply.Kind != "OK" {
+		return fmt.Errorf("Failed to start command sequence: %v", reply)
+	}
+
+	return nil
+}
+
+// End the command sequence, the reply is ERR if the layout changes failed to apply
+func endSequence(c *socket.Conn) (socket.Message, error) {
+	err := c.Send("END")
+	if err != nil {
+		return socket.Message{}, err
+	}
+
+	return c.Receive()
+}
+
+// Ask the daemon to send results in the format, the default one is text
+func requestFormat(c *socket.Conn, format string) error {
+	if format == "text" {
+		return nil
+	}
+
+	err := c.Send("FORMAT", format)
+	if err != nil {
+		return err
+	}
+
+	reply, err := c.Receive()
+	if err != nil {
+		return err
+	}
+	if reply.Kind != "OK" {
+		return fmt.Errorf("Failed to set %v format: %v", format, reply)
+	}
+
+	return nil
+}
@@ -0,0 +1,79 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/Alnivel/zentile/internal/config"
+	"github.com/Alnivel/zentile/internal/protocol"
+	log "github.com/sirupsen/logrus"
+)
+
+type jsonEvent struct {
+	Event string   `json:"event"`
+	Args  []string `json:"args"`
+}
+
+// Subscribe prints events with provided names, or all events if none provided,
+// as they happen until the daemon closes the connection
+func Subscribe(config config.Config, events []string, options Options) {
+	statusCode := OK
+	defer func() {
+		os.Exit(statusCode)
+	}()
+
+	c, err := dialDaemon(options)
+	if err != nil {
+		log.Error(err.Error())
+		statusCode = SOCKET_ERROR
+		return
+	}
+	defer c.Close()
+
+	err = c.Send("SUBSCRIBE", events...)
+	if err != nil {
+		log.Error(err.Error())
+		statusCode = SOCKET_ERROR
+		return
+	}
+
+	reply, err := c.Receive()
+	if err != nil {
+		log.Error(err.Error())
+		statusCode = SOCKET_ERROR
+		return
+	}
+	if reply.Kind != "OK" {
+		code, text := protocol.ReplyError(reply)
+		log.Errorf("Failed to subscribe: %v", text)
+		statusCode = errorCodeStatus(code)
+		return
+	}
+
+	for {
+		message, err := c.Receive()
+		if errors.Is(err, io.EOF) {
+			return
+		} else if err != nil {
+			log.Error(err.Error())
+			statusCode = SOCKET_ERROR
+			return
+		}
+
+		if message.Kind != "EVENT" || len(message.Args) == 0 {
+			log.Warnf("Unexpected message %v", message)
+			continue
+		}
+
+		if options.JSON {
+			encoded, _ := json.Marshal(jsonEvent{Event: message.Args[0], Args: message.Args[1:]})
+			fmt.Println(string(encoded))
+		} else {
+			fmt.Println(strings.Join(message.Args, " "))
+		}
+	}
+}
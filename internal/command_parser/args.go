@@ -0,0 +1,126 @@
+package commandparser
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/Alnivel/zentile/internal/types"
+)
+
+var InvalidArgument = errors.New("Invalid argument")
+
+// Values of ArgBool arguments
+var SwitchValues = []string{"on", "off"}
+
+// Prefixes of the window selectors with a parameter
+const (
+	IndexSelectorPrefix    = "%index:"
+	ClassMatcherPrefix     = "class:"
+	TitleMatcherPrefix     = "title~="
+	WorkspaceMatcherPrefix = "workspace:"
+)
+
+// Check arguments against the specs of the command, the specs are matched by position.
+// Arguments without a spec are accepted as they are.
+func ValidateArgs(specs []types.ArgSpec, args []string) error {
+	for i, arg := range args {
+		if i >= len(specs) {
+			break
+		}
+		if err := ValidateArg(specs[i], arg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Check that the value is acceptable for the argument.
+// Values depending on the daemon state, such as window ids or mark names, are only checked for syntax.
+func ValidateArg(spec types.ArgSpec, value string) error {
+	var err error
+
+	switch spec.Type {
+	case types.ArgInt:
+		_, err = ParseInt(spec.Name, value)
+	case types.ArgFloat:
+		_, err = ParseFloat(spec.Name, value)
+	case types.ArgBool:
+		_, err = ParseSwitch(spec.Name, value)
+	case types.ArgEnum:
+		if !slices.Contains(spec.Values, value) {
+			err = invalidArg(spec.Name, value, "one of "+strings.Join(spec.Values, ", "))
+		}
+	case types.ArgWindow:
+		err = validateWindow(spec.Name, value)
+	case types.ArgWorkspace, types.ArgMark:
+		if strings.TrimPrefix(value, "%") == "" {
+			err = invalidArg(spec.Name, value, "non-empty "+string(spec.Type))
+		}
+	}
+
+	return err
+}
+
+// Parse integer argument NAME
+func ParseInt(name, value string) (int, error) {
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, invalidArg(name, value, "integer")
+	}
+	return n, nil
+}
+
+// Parse floating point argument NAME
+func ParseFloat(name, value string) (float64, error) {
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, invalidArg(name, value, "number")
+	}
+	return f, nil
+}
+
+// Parse "on" or "off" value of switch argument NAME
+func ParseSwitch(name, value string) (bool, error) {
+	switch value {
+	case "on":
+		return true, nil
+	case "off":
+		return false, nil
+	default:
+		return false, invalidArg(name, value, "on or off")
+	}
+}
+
+// Check syntax of window id or window selector
+func validateWindow(name, value string) error {
+	switch {
+	case strings.HasPrefix(value, IndexSelectorPrefix):
+		if _, err := strconv.Atoi(strings.TrimPrefix(value, IndexSelectorPrefix)); err != nil {
+			return invalidArg(name, value, "integer index")
+		}
+	case strings.HasPrefix(value, TitleMatcherPrefix):
+		if _, err := regexp.Compile(strings.TrimPrefix(value, TitleMatcherPrefix)); err != nil {
+			return invalidArg(name, value, "regular expression")
+		}
+	case strings.HasPrefix(value, WorkspaceMatcherPrefix):
+		return ValidateArg(types.ArgSpec{Name: name, Type: types.ArgWorkspace}, strings.TrimPrefix(value, WorkspaceMatcherPrefix))
+	case strings.HasPrefix(value, ClassMatcherPrefix):
+	case strings.HasPrefix(value, "%"):
+		if value == "%" {
+			return invalidArg(name, value, "selector name after %")
+		}
+	default:
+		if _, err := strconv.ParseUint(value, 0, 32); err != nil {
+			return invalidArg(name, value, "window id or selector")
+		}
+	}
+	return nil
+}
+
+func invalidArg(name, value, expected string) error {
+	return fmt.Errorf("%w %v \"%v\": expected %v", InvalidArgument, name, value, expected)
+}
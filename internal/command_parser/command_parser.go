@@ -11,28 +11,42 @@ import (
 )
 
 var (
-	TooFewArguments = errors.New("Too few argument provided")
-	UnknownCommand  = errors.New("Unknown command")
+	TooFewArguments    = errors.New("Too few argument provided")
+	UnknownCommand     = errors.New("Unknown command")
+	MissingBlock       = errors.New("Expected block of commands in braces")
+	UnterminatedBlock  = errors.New("Unterminated block")
+	UnexpectedBlockEnd = errors.New("Closing brace without opening one")
 )
 
-const COMMAND_SEPARATOR = ","
+const (
+	COMMAND_SEPARATOR = ","
+	BLOCK_START       = "{"
+	BLOCK_END         = "}"
+)
 
 type CommandWrap interface {
 	MinIn() int
 	MaxIn() int
 	ValidateArgCount(count int) error
+	ArgSpecs(count int) []types.ArgSpec // Specs of the count provided arguments
 }
 
 type CommandParser struct {
 	GetCommandByName func(kind types.CommandType, name string) (CommandWrap, bool)
 }
 
-// Parse provided string into slice of commands. 
-// The commnad string are split by spaces and commas. 
+// Parse provided string into slice of commands.
+// The commnad string are split by spaces and commas.
 // A command implicitly takes up to the maximal valid number of arguments for the command
 // while comma explicitly denotes the end of arguments for the command.
+// Arguments containing spaces or commas can be quoted or escaped with backslash,
+// errors are reported as ParseError with position of the problem in the string.
 func (parser CommandParser) ParseString(s string) ([]types.Command, error) {
-	return parser.ParseSeq(strings.SplitSeq(s, " "))
+	tokens, err := tokenize(s)
+	if err != nil {
+		return nil, err
+	}
+	return parser.parseTokens(slices.Values(tokens))
 }
 
 // Parse provided slice of strings into slice of commands.
@@ -48,9 +62,11 @@ func (parser CommandParser) ParseSlice(s []string) ([]types.Command, error) {
 // A command implicitly takes up to the maximal valid number of arguments for the command
 // while comma explicitly denotes the end of arguments for the command.
 func (parser CommandParser) ParseSeq(it iter.Seq[string]) ([]types.Command, error) {
-	getNextToken, stopTokenIter := iter.Pull(
-		resplitSeq( it, splitAtSeq, COMMAND_SEPARATOR,
-		))
+	return parser.parseTokens(tokensOfSeq(it))
+}
+
+func (parser CommandParser) parseTokens(it iter.Seq[token]) ([]types.Command, error) {
+	getNextToken, stopTokenIter := iter.Pull(it)
 	defer stopTokenIter()
 
 	commands := make([]types.Command, 0)
@@ -63,34 +79,44 @@ func (parser CommandParser) ParseSeq(it iter.Seq[string]) ([]types.Command, erro
 		var command types.Command
 		var err error
 
-		switch currentToken {
-		case COMMAND_SEPARATOR:
+		switch {
+		case currentToken.is(COMMAND_SEPARATOR):
 			continue
-		case "set":
+		case currentToken.is("set"):
 			fallthrough
-		case "query":
+		case currentToken.is("query"):
 			fallthrough
-		case "for":
-			kind := currentToken
+		case currentToken.is("for"):
+			kind := currentToken.value
 
 			currentArg, exists := getNextToken()
-			if !exists || currentArg == COMMAND_SEPARATOR {
-				return nil, fmt.Errorf(
+			if !exists || currentArg.is(COMMAND_SEPARATOR) {
+				err = fmt.Errorf(
 					"%w: %v name is not provided",
 					TooFewArguments, kind,
 				)
+				break
 			}
-			name := currentArg
+			name := currentArg.value
 
 			command, err = parser.parseCommand(kind, name, getNextToken)
+		case currentToken.is("foreach"):
+			command, err = parser.parseForeach(currentToken, getNextToken)
+		case currentToken.is(BLOCK_END):
+			err = UnexpectedBlockEnd
 		default:
 			kind := "action"
-			name := currentToken
+			name := currentToken.value
 
 			command, err = parser.parseCommand(kind, name, getNextToken)
 		}
 
 		if err != nil {
+			// Errors inside blocks already have the position
+			var parseErr ParseError
+			if currentToken.pos > 0 && !errors.As(err, &parseErr) {
+				err = ParseError{Pos: currentToken.pos, Err: err}
+			}
 			return nil, err
 		}
 
@@ -105,7 +131,7 @@ func parseCommandType(arg string) types.CommandType {
 }
 
 // Parse individual command
-func (parser CommandParser) parseCommand(commandKind string, commandName string, getNextArg func() (string, bool)) (types.Command, error) {
+func (parser CommandParser) parseCommand(commandKind string, commandName string, getNextArg func() (token, bool)) (types.Command, error) {
 	commandType := parseCommandType(commandKind)
 	command, exists := parser.GetCommandByName(commandType, commandName)
 	if exists == false {
@@ -115,11 +141,30 @@ func (parser CommandParser) parseCommand(commandKind string, commandName string,
 		)
 	}
 
-	commandArgs, _ := pullUntilSepOrN(getNextArg, COMMAND_SEPARATOR, command.MaxIn())
-	if err := command.ValidateArgCount(len(commandArgs)); err != nil {
+	isSeparator := func(t token) bool {
+		return t.is(COMMAND_SEPARATOR)
+	}
+	argTokens, _ := pullUntilSepOrN(getNextArg, isSeparator, command.MaxIn())
+	if err := command.ValidateArgCount(len(argTokens)); err != nil {
 		return types.Command{}, err
 	}
 
+	commandArgs := make([]string, len(argTokens))
+	specs := command.ArgSpecs(len(argTokens))
+	for i, argToken := range argTokens {
+		commandArgs[i] = argToken.value
+
+		if i >= len(specs) {
+			continue
+		}
+		if err := ValidateArg(specs[i], argToken.value); err != nil {
+			if argToken.pos > 0 {
+				err = ParseError{Pos: argToken.pos, Err: err}
+			}
+			return types.Command{}, err
+		}
+	}
+
 	return types.Command{
 		Kind: commandType,
 		Name: commandName,
@@ -127,15 +172,98 @@ func (parser CommandParser) parseCommand(commandKind string, commandName string,
 	}, nil
 }
 
+// Parse foreach command: foreach NAME [FILTER] { COMMANDS }.
+// The block is validated and passed to the command as the last argument in canonical form.
+func (parser CommandParser) parseForeach(foreachToken token, getNextToken func() (token, bool)) (types.Command, error) {
+	const kind = "foreach"
+
+	nameToken, exists := getNextToken()
+	if !exists || nameToken.is(COMMAND_SEPARATOR) || nameToken.is(BLOCK_START) {
+		return types.Command{}, fmt.Errorf("%w: %v name is not provided", TooFewArguments, kind)
+	}
+
+	commandType := parseCommandType(kind)
+	command, exists := parser.GetCommandByName(commandType, nameToken.value)
+	if !exists {
+		return types.Command{}, fmt.Errorf("%w: %v %v", UnknownCommand, kind, nameToken.value)
+	}
+
+	args := make([]string, 0, 2)
+	nextToken, exists := getNextToken()
+	filterToken := nextToken
+	if exists && !nextToken.is(BLOCK_START) && !nextToken.is(COMMAND_SEPARATOR) {
+		// Filter
+		args = append(args, nextToken.value)
+		nextToken, exists = getNextToken()
+	}
+	if !exists || !nextToken.is(BLOCK_START) {
+		return types.Command{}, MissingBlock
+	}
+
+	blockTokens, err := pullBlock(nextToken, getNextToken)
+	if err != nil {
+		return types.Command{}, err
+	}
+	blockCommands, err := parser.parseTokens(slices.Values(blockTokens))
+	if err != nil {
+		return types.Command{}, err
+	}
+	args = append(args, FormatCommands(blockCommands))
+
+	if err := command.ValidateArgCount(len(args)); err != nil {
+		return types.Command{}, err
+	}
+	if err := ValidateArgs(command.ArgSpecs(len(args)), args); err != nil {
+		if len(args) == 2 && filterToken.pos > 0 {
+			err = ParseError{Pos: filterToken.pos, Err: err}
+		}
+		return types.Command{}, err
+	}
+
+	return types.Command{
+		Kind: commandType,
+		Name: nameToken.value,
+		Args: args,
+	}, nil
+}
+
+// Collect tokens until the brace closing the already pulled opening one
+func pullBlock(startToken token, getNextToken func() (token, bool)) ([]token, error) {
+	tokens := make([]token, 0)
+	depth := 1
+	for {
+		currentToken, exists := getNextToken()
+		if !exists {
+			err := error(UnterminatedBlock)
+			if startToken.pos > 0 {
+				err = ParseError{Pos: startToken.pos, Err: err}
+			}
+			return nil, err
+		}
+
+		switch {
+		case currentToken.is(BLOCK_START):
+			depth++
+		case currentToken.is(BLOCK_END):
+			depth--
+		}
+		if depth == 0 {
+			return tokens, nil
+		}
+
+		tokens = append(tokens, currentToken)
+	}
+}
+
 // Call getNextElem function n times or until
-// the function returns false or sep.
+// the function returns false or separator.
 // Returns slice of the collected values and
 // status if all requested n values was collected
-func pullUntilSepOrN[T comparable](getNextElem func() (T, bool), sep T, n int) ([]T, bool) {
+func pullUntilSepOrN[T any](getNextElem func() (T, bool), isSep func(T) bool, n int) ([]T, bool) {
 	result := make([]T, 0, n)
 	for range n {
 		value, exists := getNextElem()
-		if !exists || value == sep {
+		if !exists || isSep(value) {
 			return result, false
 		}
 
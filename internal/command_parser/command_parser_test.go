@@ -0,0 +1,179 @@
+package commandparser
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/Alnivel/zentile/internal/types"
+)
+
+type testCommand struct {
+	minIn, maxIn int
+	args         []types.ArgSpec
+}
+
+func (c testCommand) MinIn() int { return c.minIn }
+func (c testCommand) MaxIn() int { return c.maxIn }
+func (c testCommand) ArgSpecs(count int) []types.ArgSpec {
+	return c.args[:min(count, len(c.args))]
+}
+func (c testCommand) ValidateArgCount(count int) error {
+	if count < c.minIn {
+		return fmt.Errorf("%w: expected at least %d", TooFewArguments, c.minIn)
+	}
+	return nil
+}
+
+var testCommands = map[types.CommandType]map[string]testCommand{
+	types.Action: {
+		"tile":      {0, 0, nil},
+		"swap":      {1, 2, nil},
+		"set_title": {1, 1, nil},
+		"focus":     {1, 1, []types.ArgSpec{{Name: "WID", Type: types.ArgWindow}}},
+	},
+	types.Set: {
+		"layout":     {1, 1, []types.ArgSpec{{Name: "LAYOUT_NAME", Type: types.ArgEnum, Values: []string{"vertical"}}}},
+		"gap":        {1, 1, []types.ArgSpec{{Name: "GAP", Type: types.ArgInt}}},
+		"proportion": {1, 1, []types.ArgSpec{{Name: "PROPORTION", Type: types.ArgFloat}}},
+		"start":      {1, 1, []types.ArgSpec{{Name: "SWITCH", Type: types.ArgBool, Values: SwitchValues}}},
+	},
+	types.Foreach: {
+		"window": {1, 2, nil},
+	},
+}
+
+var testParser = CommandParser{
+	GetCommandByName: func(kind types.CommandType, name string) (CommandWrap, bool) {
+		command, exists := testCommands[kind][name]
+		return command, exists
+	},
+}
+
+func action(name string, args ...string) types.Command {
+	if args == nil {
+		args = []string{}
+	}
+	return types.Command{Kind: types.Action, Name: name, Args: args}
+}
+
+func foreach(name string, args ...string) types.Command {
+	return types.Command{Kind: types.Foreach, Name: name, Args: args}
+}
+
+func TestCommandParser_ParseString(t *testing.T) {
+	tests := []struct {
+		name string
+
+		input   string
+		want    []types.Command
+		wantErr error
+		wantPos int
+	}{
+		// Plain sequences
+		{"Single", "tile", []types.Command{action("tile")}, nil, 0},
+		{"Greedy", "swap 1 2 tile", []types.Command{action("swap", "1", "2"), action("tile")}, nil, 0},
+		{"Separator", "swap 1, tile", []types.Command{action("swap", "1"), action("tile")}, nil, 0},
+		{"AttachedSeparator", "swap 1,tile", []types.Command{action("swap", "1"), action("tile")}, nil, 0},
+		{"Keyword", "set layout vertical", []types.Command{{Kind: types.Set, Name: "layout", Args: []string{"vertical"}}}, nil, 0},
+		{"ExtraSpaces", "  tile \t ", []types.Command{action("tile")}, nil, 0},
+		// Quotes and escapes
+		{"DoubleQuotes", `set_title "a b, c"`, []types.Command{action("set_title", "a b, c")}, nil, 0},
+		{"SingleQuotes", `set_title 'a "b" \c'`, []types.Command{action("set_title", `a "b" \c`)}, nil, 0},
+		{"EscapeInDoubleQuotes", `set_title "a \"b\" \\c"`, []types.Command{action("set_title", `a "b" \c`)}, nil, 0},
+		{"EscapedSpace", `set_title a\ b`, []types.Command{action("set_title", "a b")}, nil, 0},
+		{"EscapedSeparator", `swap \, tile`, []types.Command{action("swap", ",", "tile")}, nil, 0},
+		{"QuotedSeparator", `swap "," tile`, []types.Command{action("swap", ",", "tile")}, nil, 0},
+		{"Concatenated", `set_title a"b c"'d'`, []types.Command{action("set_title", "ab cd")}, nil, 0},
+		{"EmptyQuotes", `set_title ""`, []types.Command{action("set_title", "")}, nil, 0},
+		{"QuotedKeyword", `"set" 1`, nil, UnknownCommand, 1},
+		// Blocks
+		{"Foreach", "foreach window { tile, swap 1 }", []types.Command{foreach("window", "tile, swap 1")}, nil, 0},
+		{"ForeachFilter", `foreach window class:term { set_title "a b" }`, []types.Command{foreach("window", "class:term", "set_title 'a b'")}, nil, 0},
+		{"ForeachAttachedBraces", "foreach window {tile}, tile", []types.Command{foreach("window", "tile"), action("tile")}, nil, 0},
+		{"ForeachNested", "foreach window { foreach window { tile } }", []types.Command{foreach("window", "foreach window { tile }")}, nil, 0},
+		{"ForeachEmpty", "foreach window {}", []types.Command{foreach("window", "")}, nil, 0},
+		{"QuotedBraces", `set_title "{}"`, []types.Command{action("set_title", "{}")}, nil, 0},
+		{"MissingBlock", "foreach window tile", nil, MissingBlock, 1},
+		{"UnterminatedBlock", "foreach window { tile", nil, UnterminatedBlock, 16},
+		{"UnexpectedBlockEnd", "tile }", nil, UnexpectedBlockEnd, 6},
+		{"ErrorInBlock", "foreach window { bogus }", nil, UnknownCommand, 18},
+		// Errors
+		{"UnterminatedDouble", `tile, set_title "abc`, nil, UnterminatedQuote, 17},
+		{"UnterminatedSingle", `set_title 'abc`, nil, UnterminatedQuote, 11},
+		{"DanglingEscape", `set_title abc\`, nil, DanglingEscape, 14},
+		{"UnknownCommand", "tile, unknown", nil, UnknownCommand, 7},
+		{"TooFewArguments", "tile swap", nil, TooFewArguments, 6},
+		{"MissingName", "tile, set", nil, TooFewArguments, 7},
+		{"PositionCountsCharacters", "set_title ☺, bogus", nil, UnknownCommand, 14},
+		// Typed arguments
+		{"ValidArgs", "set gap 5, set proportion 0.6, set start on", []types.Command{
+			{Kind: types.Set, Name: "gap", Args: []string{"5"}},
+			{Kind: types.Set, Name: "proportion", Args: []string{"0.6"}},
+			{Kind: types.Set, Name: "start", Args: []string{"on"}},
+		}, nil, 0},
+		{"Selectors", "focus 0x1a, focus %index:-1, focus title~=^a.b$, focus class:term", []types.Command{
+			action("focus", "0x1a"), action("focus", "%index:-1"), action("focus", "title~=^a.b$"), action("focus", "class:term"),
+		}, nil, 0},
+		{"InvalidInt", "tile, set gap five", nil, InvalidArgument, 15},
+		{"InvalidFloat", "set proportion half", nil, InvalidArgument, 16},
+		{"InvalidSwitch", "set start yes", nil, InvalidArgument, 11},
+		{"InvalidEnum", "set layout diagonal", nil, InvalidArgument, 12},
+		{"InvalidWindowId", "focus firefox", nil, InvalidArgument, 7},
+		{"InvalidIndex", "focus %index:x", nil, InvalidArgument, 7},
+		{"InvalidTitleRegex", "focus title~=(", nil, InvalidArgument, 7},
+		{"InvalidArgInBlock", "foreach window { set gap x }", nil, InvalidArgument, 26},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := testParser.ParseString(tt.input)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("ParseString() error = %v, want %v", err, tt.wantErr)
+				}
+				var parseErr ParseError
+				if !errors.As(err, &parseErr) || parseErr.Pos != tt.wantPos {
+					t.Fatalf("ParseString() error = %v, want position %d", err, tt.wantPos)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseString() unexpected error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseString() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCommandParser_ParseSlice(t *testing.T) {
+	// Already split arguments are taken as is except for the commas
+	got, err := testParser.ParseSlice([]string{"set_title", "a b", "swap", "1,tile"})
+	if err != nil {
+		t.Fatalf("ParseSlice() unexpected error = %v", err)
+	}
+	want := []types.Command{action("set_title", "a b"), action("swap", "1"), action("tile")}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseSlice() = %#v, want %#v", got, want)
+	}
+
+	// Braces are resplit as well
+	got, err = testParser.ParseSlice([]string{"foreach", "window", "{tile,", "swap", "1}"})
+	if err != nil {
+		t.Fatalf("ParseSlice() unexpected error = %v", err)
+	}
+	want = []types.Command{foreach("window", "tile, swap 1")}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseSlice() = %#v, want %#v", got, want)
+	}
+
+	var parseErr ParseError
+	if _, err := testParser.ParseSlice([]string{"unknown"}); errors.As(err, &parseErr) {
+		t.Errorf("ParseSlice() error = %v, position is unknown for slices", err)
+	}
+}
@@ -0,0 +1,40 @@
+package commandparser
+
+import (
+	"strings"
+
+	"github.com/Alnivel/zentile/internal/types"
+)
+
+// Format commands as a string that ParseString parses back into the same commands
+func FormatCommands(commands []types.Command) string {
+	formatted := make([]string, len(commands))
+	for i, command := range commands {
+		formatted[i] = FormatCommand(command)
+	}
+	return strings.Join(formatted, COMMAND_SEPARATOR+" ")
+}
+
+// Format command as a string that ParseString parses back into the same command
+func FormatCommand(command types.Command) string {
+	words := make([]string, 0, len(command.Args)+4)
+	if command.Kind != types.Action {
+		words = append(words, strings.ToLower(string(command.Kind)))
+	}
+	words = append(words, quoteIfNeeded(command.Name))
+
+	args := command.Args
+	if command.Kind == types.Foreach && len(args) > 0 {
+		// The last argument is the block, which is already formatted
+		for _, arg := range args[:len(args)-1] {
+			words = append(words, quoteIfNeeded(arg))
+		}
+		words = append(words, BLOCK_START, args[len(args)-1], BLOCK_END)
+	} else {
+		for _, arg := range args {
+			words = append(words, quoteIfNeeded(arg))
+		}
+	}
+
+	return strings.Join(words, " ")
+}
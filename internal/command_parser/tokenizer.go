@@ -0,0 +1,146 @@
+package commandparser
+
+import (
+	"errors"
+	"fmt"
+	"iter"
+	"strings"
+	"unicode"
+)
+
+var (
+	UnterminatedQuote = errors.New("Unterminated quote")
+	DanglingEscape    = errors.New("Nothing to escape at the end of the string")
+)
+
+// ParseError is an error at the known position of the parsed string
+type ParseError struct {
+	Pos int // Position of the character, starting from 1
+	Err error
+}
+
+func (e ParseError) Error() string {
+	return fmt.Sprintf("At position %d: %v", e.Pos, e.Err)
+}
+
+func (e ParseError) Unwrap() error {
+	return e.Err
+}
+
+type token struct {
+	value   string
+	literal bool // Token was quoted or escaped, so it is never a separator or keyword
+	pos     int  // Position of the token in the parsed string or 0 if unknown
+}
+
+func (t token) is(value string) bool {
+	return !t.literal && t.value == value
+}
+
+// Split string into tokens by spaces, commas and braces, the commas and braces become tokens as well.
+// Single quotes preserve everything inside them, double quotes preserve
+// everything except backslash, which escapes the next character.
+// Outside of quotes backslash escapes the next character too.
+func tokenize(s string) ([]token, error) {
+	tokens := make([]token, 0)
+
+	var current strings.Builder
+	var currentToken *token
+
+	var quote rune
+	quotePos := 0
+	escaped := false
+	escapePos := 0
+
+	continueToken := func(pos int) {
+		if currentToken == nil {
+			currentToken = &token{pos: pos}
+		}
+	}
+	endToken := func() {
+		if currentToken != nil {
+			currentToken.value = current.String()
+			tokens = append(tokens, *currentToken)
+			currentToken = nil
+			current.Reset()
+		}
+	}
+
+	pos := 0
+	for _, r := range s {
+		pos++
+
+		switch {
+		case escaped:
+			current.WriteRune(r)
+			escaped = false
+		case quote != 0:
+			switch {
+			case r == quote:
+				quote = 0
+			case r == '\\' && quote == '"':
+				escaped, escapePos = true, pos
+			default:
+				current.WriteRune(r)
+			}
+		case r == '\\':
+			continueToken(pos)
+			currentToken.literal = true
+			escaped, escapePos = true, pos
+		case r == '\'' || r == '"':
+			continueToken(pos)
+			currentToken.literal = true
+			quote, quotePos = r, pos
+		case unicode.IsSpace(r):
+			endToken()
+		case string(r) == COMMAND_SEPARATOR || string(r) == BLOCK_START || string(r) == BLOCK_END:
+			endToken()
+			tokens = append(tokens, token{value: string(r), pos: pos})
+		default:
+			continueToken(pos)
+			current.WriteRune(r)
+		}
+	}
+
+	if escaped {
+		return nil, ParseError{Pos: escapePos, Err: DanglingEscape}
+	}
+	if quote != 0 {
+		return nil, ParseError{Pos: quotePos, Err: UnterminatedQuote}
+	}
+	endToken()
+
+	return tokens, nil
+}
+
+// Resplit the strings by separator and braces into tokens with unknown position
+func tokensOfSeq(it iter.Seq[string]) iter.Seq[token] {
+	it = resplitSeq(it, splitAtSeq, COMMAND_SEPARATOR)
+	it = resplitSeq(it, splitAtSeq, BLOCK_START)
+	it = resplitSeq(it, splitAtSeq, BLOCK_END)
+
+	return func(yield func(token) bool) {
+		for value := range it {
+			if !yield(token{value: value}) {
+				return
+			}
+		}
+	}
+}
+
+// Quote the string, so ParseString takes it as a single argument as is
+func Quote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// Quote the string only if ParseString would not take it as a single argument as is
+func quoteIfNeeded(s string) string {
+	if s == "" || strings.ContainsFunc(s, needsQuoting) {
+		return Quote(s)
+	}
+	return s
+}
+
+func needsQuoting(r rune) bool {
+	return unicode.IsSpace(r) || strings.ContainsRune(`'"\`+COMMAND_SEPARATOR+BLOCK_START+BLOCK_END, r)
+}
@@ -1,11 +1,24 @@
 package config
 
 import (
+	"errors"
+	"fmt"
+	"slices"
 	"strconv"
+	"time"
 
+	"github.com/Alnivel/zentile/internal/socket"
 	log "github.com/sirupsen/logrus"
 )
 
+var (
+	InvalidGap        = errors.New("Gap must not be negative")
+	InvalidProportion = errors.New("Proportion must be between 0 and 1")
+	InvalidLayoutName = errors.New("Invalid layout name")
+	DuplicateLayout   = errors.New("Duplicate layout name")
+	NoLayouts         = errors.New("No valid layout names provided")
+)
+
 type workspaceConfigRaw struct {
 	StartTiling *bool `toml:"start_tiling"`
 	Gap         *int
@@ -17,9 +30,14 @@ type workspaceConfigRaw struct {
 type configRaw struct {
 	WorkspaceConfigs map[string]workspaceConfigRaw `toml:"workspace"`
 
-	ProportionStep  *float64
-	Keybindings     map[string]string
-	WindowsToIgnore []string `toml:"ignore"`
+	ProportionStep    *float64
+	AnimationDuration *int    `toml:"animation_duration"`
+	MaxMessageLength  *int    `toml:"max_message_length"`
+	StickyWindows     *string `toml:"sticky_windows"`
+	TransientWindows  *string `toml:"transient_windows"`
+	Keybindings       map[string]string
+	Aliases           map[string]string
+	WindowsToIgnore   []string `toml:"ignore"`
 }
 
 type WorkspaceConfig struct {
@@ -34,9 +52,14 @@ type Config struct {
 	globalWorkspaceConfig WorkspaceConfig
 	workspaceConfigs      map[uint]WorkspaceConfig
 
-	ProportionStep  float64
-	Keybindings     map[string]string
-	WindowsToIgnore []string
+	ProportionStep    float64
+	AnimationDuration time.Duration
+	MaxMessageLength  int // Limit of the messages received by the daemon and the CLI in bytes
+	StickyWindows     string
+	TransientWindows  string
+	Keybindings       map[string]string
+	Aliases           map[string]string
+	WindowsToIgnore   []string
 }
 
 func newWorkspaceConfigFromRaw(raw workspaceConfigRaw, defaults WorkspaceConfig) WorkspaceConfig {
@@ -61,7 +84,11 @@ func newWorkspaceConfigFromRaw(raw workspaceConfigRaw, defaults WorkspaceConfig)
 	return config
 }
 
-var defaultLayoutOrder = []string{"vertical", "horizontal", "fullscreen"}
+// Smallest accepted limit of the message length, enough for any command and the handshake
+const minMessageLength = 1 << 10
+
+// Names of all the layouts in the default order
+var LayoutNames = []string{"vertical", "horizontal", "fullscreen"}
 
 func newConfigFromRaw(raw configRaw) (Config, error) {
 	handleLegacyKeybindings(&raw)
@@ -71,7 +98,7 @@ func newConfigFromRaw(raw configRaw) (Config, error) {
 		Gap:         5,
 		Proportion:  0.5,
 		HideDecor:   false,
-		Layouts:     defaultLayoutOrder,
+		Layouts:     LayoutNames,
 	}
 
 	globalWsConfig := newWorkspaceConfigFromRaw(raw.WorkspaceConfigs["defaults"], wsDefaults)
@@ -94,13 +121,61 @@ func newConfigFromRaw(raw configRaw) (Config, error) {
 		proportionStep = *raw.ProportionStep
 	}
 
+	// Animation is disabled unless explicitly enabled
+	var animationDuration time.Duration
+	if raw.AnimationDuration != nil {
+		if *raw.AnimationDuration < 0 {
+			log.Warnf("Error during parsing config: Negative animation duration %v", *raw.AnimationDuration)
+		} else {
+			animationDuration = time.Duration(*raw.AnimationDuration) * time.Millisecond
+		}
+	}
+
+	maxMessageLength := socket.DEFAULT_MAX_MESSAGE_LENGTH
+	if raw.MaxMessageLength != nil {
+		if *raw.MaxMessageLength < minMessageLength {
+			log.Warnf("Error during parsing config: Max message length %v is less than %v", *raw.MaxMessageLength, minMessageLength)
+		} else {
+			maxMessageLength = *raw.MaxMessageLength
+		}
+	}
+
+	stickyWindows := "ignore"
+	if raw.StickyWindows != nil {
+		switch *raw.StickyWindows {
+		case "ignore":
+			fallthrough
+		case "follow":
+			stickyWindows = *raw.StickyWindows
+		default:
+			log.Warnf("Error during parsing config: Invalid sticky windows policy %v", *raw.StickyWindows)
+		}
+	}
+
+	transientWindows := "ignore"
+	if raw.TransientWindows != nil {
+		switch *raw.TransientWindows {
+		case "ignore":
+			fallthrough
+		case "center":
+			transientWindows = *raw.TransientWindows
+		default:
+			log.Warnf("Error during parsing config: Invalid transient windows policy %v", *raw.TransientWindows)
+		}
+	}
+
 	return Config{
 		globalWorkspaceConfig: globalWsConfig,
 		workspaceConfigs:      workspaceConfigs,
 
-		ProportionStep:  proportionStep,
-		Keybindings:     raw.Keybindings,
-		WindowsToIgnore: raw.WindowsToIgnore,
+		ProportionStep:    proportionStep,
+		AnimationDuration: animationDuration,
+		MaxMessageLength:  maxMessageLength,
+		StickyWindows:     stickyWindows,
+		TransientWindows:  transientWindows,
+		Keybindings:       raw.Keybindings,
+		Aliases:           raw.Aliases,
+		WindowsToIgnore:   raw.WindowsToIgnore,
 	}, nil
 }
 
@@ -113,17 +188,52 @@ func (c Config) WorkspaceConfig(num uint) WorkspaceConfig {
 	}
 }
 
+func ValidateGap(gap int) error {
+	if gap < 0 {
+		return fmt.Errorf("%w: %v", InvalidGap, gap)
+	}
+	return nil
+}
+
+func ValidateProportion(proportion float64) error {
+	if proportion <= 0 || proportion >= 1 {
+		return fmt.Errorf("%w: %v", InvalidProportion, proportion)
+	}
+	return nil
+}
+
+// Returns the valid layout names from the list
+// and error describing every invalid or duplicate one.
+// Used by the setters, the config file is checked by validateLayoutsList.
+func ValidateLayouts(list []string) ([]string, error) {
+	var result []string
+	var errs []error
+	for _, layoutName := range list {
+		switch {
+		case !slices.Contains(LayoutNames, layoutName):
+			errs = append(errs, fmt.Errorf("%w: %v", InvalidLayoutName, layoutName))
+		case slices.Contains(result, layoutName):
+			errs = append(errs, fmt.Errorf("%w: %v", DuplicateLayout, layoutName))
+		default:
+			result = append(result, layoutName)
+		}
+	}
+
+	if result == nil {
+		errs = append(errs, NoLayouts)
+	}
+
+	return result, errors.Join(errs...)
+}
+
+// Layouts of the config file, unlike ValidateLayouts it keeps duplicates
+// and uses the defaults if none of the names is valid
 func validateLayoutsList(list []string) []string {
 	var result []string
 	for _, layoutName := range list {
-		switch layoutName {
-		case "vertical":
-			fallthrough
-		case "horizontal":
-			fallthrough
-		case "fullscreen":
+		if slices.Contains(LayoutNames, layoutName) {
 			result = append(result, layoutName)
-		default:
+		} else {
 			log.Warnf("Invalid layout name %v", layoutName)
 		}
 	}
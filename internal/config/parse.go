@@ -57,6 +57,22 @@ var defaultConfig = `
 # How much to increment the master area size.
 proportionStep = 0.04
 
+# Duration of the transition between layouts in milliseconds.
+# Windows are moved instantly if set to 0 or omitted.
+# animation_duration = 150
+
+# Limit of the messages in bytes, a single argument such as a script line can take all of it.
+# The daemon disconnects the clients sending longer ones, the CLI refuses longer replies.
+# max_message_length = 4194304
+
+# How to handle windows shown on all desktops (sticky windows).
+# "ignore" leaves them untouched, "follow" tiles them in the current workspace.
+# sticky_windows = "ignore"
+
+# How to handle transient windows such as dialogs, they are never tiled.
+# "ignore" leaves them where they are, "center" centers them over their parent window.
+# transient_windows = "ignore"
+
 # Zentile will ignore windows added to this list.
 # You'll have to add WM_CLASS property of the window you want ignored.
 # You can get WM_CLASS property of a window, by running "xprop WM_CLASS" and clicking on the window.
@@ -80,6 +96,12 @@ gap = 0
 [workspace.2]
 proportion = 0.6
 
+# ===== Aliases =====
+# New actions made of command sequences, usable in keybindings and the CLI.
+# $1, $2, ... are replaced with the arguments of the alias, $$ with a dollar sign.
+[aliases]
+# focus_and_master = "for window $1 make_active_window_master"
+
 # ===== Keybindings =====
 [keybindings]
 # key sequences can have zero or more modifiers and exactly one key.
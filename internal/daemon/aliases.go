@@ -0,0 +1,102 @@
+package daemon
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+
+	commandparser "github.com/Alnivel/zentile/internal/command_parser"
+	"github.com/Alnivel/zentile/internal/types"
+	log "github.com/sirupsen/logrus"
+)
+
+var AliasTooDeep = errors.New("Alias expansion is too deep")
+
+// Limits aliases calling each other, so a recursive alias fails instead of hanging the daemon
+const MAX_ALIAS_DEPTH = 16
+
+var (
+	aliasNamePattern   = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+	aliasPlaceholder   = regexp.MustCompile(`\$(\$|[0-9]+)`)
+	aliasReservedNames = []string{"set", "query", "for", "foreach"}
+)
+
+// Register aliases from the config as actions.
+// An alias takes as many arguments as the highest $N placeholder in its command sequence.
+func (c Commands) registerAliases(aliases map[string]string) {
+	depth := 0
+	parser := c.parser()
+
+	for name, sequence := range aliases {
+		if !aliasNamePattern.MatchString(name) || slices.Contains(aliasReservedNames, name) {
+			log.Warnf("Error during parsing config: Invalid alias name \"%v\"", name)
+			continue
+		}
+		if _, exists := c.Actions[name]; exists {
+			log.Warnf("Error during parsing config: Alias \"%v\" shadows built-in action, ignoring it", name)
+			continue
+		}
+
+		argCount := aliasArgCount(sequence)
+		args := make([]types.ArgSpec, argCount)
+		for i := range args {
+			args[i] = types.ArgSpec{Name: fmt.Sprintf("$%d", i+1), Type: types.ArgString}
+		}
+
+		c.Actions[name] = CommandWrap{
+			minIn: argCount, maxIn: argCount,
+			args:        args,
+			description: fmt.Sprintf("Alias for \"%v\"", sequence),
+			fn: func(args ...string) ([]string, error) {
+				if depth >= MAX_ALIAS_DEPTH {
+					return nil, fmt.Errorf("%w: %v", AliasTooDeep, name)
+				}
+				depth++
+				defer func() { depth-- }()
+
+				commands, err := parser.ParseString(expandAlias(sequence, args))
+				if err != nil {
+					return nil, fmt.Errorf("Error in alias %v: %w", name, err)
+				}
+
+				var messages []string
+				for _, command := range commands {
+					result := c.Do(command)
+					messages = append(messages, result.Messages...)
+					if result.Err != nil {
+						return messages, result.Err
+					}
+				}
+				return messages, nil
+			},
+		}
+	}
+}
+
+// Number of arguments of the alias, the highest placeholder number in its command sequence
+func aliasArgCount(sequence string) int {
+	count := 0
+	for _, match := range aliasPlaceholder.FindAllStringSubmatch(sequence, -1) {
+		if n, err := strconv.Atoi(match[1]); err == nil {
+			count = max(count, n)
+		}
+	}
+	return count
+}
+
+// Replace $N placeholders with quoted arguments and $$ with $
+func expandAlias(sequence string, args []string) string {
+	return aliasPlaceholder.ReplaceAllStringFunc(sequence, func(placeholder string) string {
+		if placeholder == "$$" {
+			return "$"
+		}
+
+		n, _ := strconv.Atoi(placeholder[1:])
+		if n < 1 || n > len(args) {
+			return placeholder
+		}
+		return commandparser.Quote(args[n-1])
+	})
+}
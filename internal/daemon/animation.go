@@ -0,0 +1,135 @@
+package daemon
+
+import (
+	"errors"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const ANIMATION_FRAME_INTERVAL = 16 * time.Millisecond
+
+// Geometry of a client including its decorations
+type Geometry struct {
+	X, Y, Width, Height int
+}
+
+type Placement struct {
+	Client Client
+	Geometry
+}
+
+// Animator moves clients into the places computed by a layout.
+// When animation is enabled the clients are moved over several frames,
+// otherwise they are moved at once.
+//
+// Frames are not applied by the animator itself but sent to the frames channel,
+// so they run in the main loop together with X events and commands.
+type Animator struct {
+	tracker  Tracker
+	duration time.Duration
+	frames   chan<- func()
+	cancel   chan struct{}
+}
+
+func NewAnimator(tracker Tracker, duration time.Duration, frames chan<- func()) *Animator {
+	return &Animator{
+		tracker:  tracker,
+		duration: duration,
+		frames:   frames,
+	}
+}
+
+// Apply moves clients to their placements, cancelling the animation in progress if any.
+// Failures of the animation frames are only logged, as they happen after Apply returns.
+func (a *Animator) Apply(placements []Placement) error {
+	a.Cancel()
+
+	if a.duration <= 0 || a.frames == nil {
+		var errs []error
+		for _, p := range placements {
+			errs = append(errs, p.Client.MoveResize(p.X, p.Y, p.Width, p.Height))
+		}
+		a.tracker.Sync()
+		return backendError(errors.Join(errs...))
+	}
+
+	starts := make([]Geometry, len(placements))
+	for i, p := range placements {
+		x, y, w, h, err := p.Client.Geometry()
+		if err != nil {
+			// Nothing to interpolate from, so jump straight to the target
+			starts[i] = p.Geometry
+		} else {
+			starts[i] = Geometry{x, y, w, h}
+		}
+	}
+
+	cancel := make(chan struct{})
+	a.cancel = cancel
+	frameCount := max(1, int(a.duration/ANIMATION_FRAME_INTERVAL))
+
+	go func() {
+		ticker := time.NewTicker(ANIMATION_FRAME_INTERVAL)
+		defer ticker.Stop()
+
+		for frame := 1; frame <= frameCount; frame++ {
+			select {
+			case <-cancel:
+				return
+			case <-ticker.C:
+			}
+
+			progress := easeOut(float64(frame) / float64(frameCount))
+			applyFrame := func() {
+				// The animation could have been cancelled
+				// while the frame was waiting in the channel
+				select {
+				case <-cancel:
+					return
+				default:
+				}
+
+				for i, p := range placements {
+					g := interpolate(starts[i], p.Geometry, progress)
+					if err := p.Client.MoveResize(g.X, g.Y, g.Width, g.Height); err != nil {
+						log.Warn(err)
+					}
+				}
+				a.tracker.Sync()
+			}
+
+			select {
+			case <-cancel:
+				return
+			case a.frames <- applyFrame:
+			}
+		}
+	}()
+	return nil
+}
+
+// Cancel stops the animation in progress, leaving the clients where they are
+func (a *Animator) Cancel() {
+	if a.cancel != nil {
+		close(a.cancel)
+		a.cancel = nil
+	}
+}
+
+func easeOut(t float64) float64 {
+	return 1 - (1-t)*(1-t)
+}
+
+func interpolate(from, to Geometry, progress float64) Geometry {
+	lerp := func(a, b int) int {
+		return a + int(float64(b-a)*progress)
+	}
+
+	return Geometry{
+		X:      lerp(from.X, to.X),
+		Y:      lerp(from.Y, to.Y),
+		Width:  lerp(from.Width, to.Width),
+		Height: lerp(from.Height, to.Height),
+	}
+}
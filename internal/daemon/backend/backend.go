@@ -6,7 +6,7 @@ type backend interface {
 
 func NewTrackerFor[T workspace](
 	backend backend,
-	classesToIgnore []string,
+	options TrackerOptions,
 	workspaceFactory WorkspaceFactory[T],
 ) (
 	Tracker[T],
@@ -15,7 +15,7 @@ func NewTrackerFor[T workspace](
 
 	switch concreteBackend := backend.(type) {
 	case x11Backend:
-		return newX11Tracker(concreteBackend, classesToIgnore, workspaceFactory)
+		return newX11Tracker(concreteBackend, options, workspaceFactory)
 	default:
 		return nil, nil
 	}
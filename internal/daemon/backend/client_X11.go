@@ -2,11 +2,13 @@ package backend
 
 import (
 	"fmt"
+	"slices"
 	"strconv"
 
 	"github.com/jezek/xgb/xproto"
 	"github.com/jezek/xgbutil"
 	"github.com/jezek/xgbutil/ewmh"
+	"github.com/jezek/xgbutil/icccm"
 	"github.com/jezek/xgbutil/motif"
 	"github.com/jezek/xgbutil/xrect"
 	"github.com/jezek/xgbutil/xwindow"
@@ -37,6 +39,10 @@ type X11Client struct {
 	id           X11ClientId
 	window       *xwindow.Window
 	workspaceNum uint // Desktop the client is currently in.
+	minimized    bool // Client is minimized.
+	fullscreen   bool // Client is fullscreen.
+	above        bool // Client is kept above other windows.
+	sticky       bool // Client is shown on all desktops.
 	savedProp    Prop // Properties that the client had, before it was tiled.
 
 	X *xgbutil.XUtil
@@ -60,19 +66,60 @@ func (c X11Client) name() string {
 	return name
 }
 
+func (c X11Client) Title() string {
+	return c.name()
+}
+
+// Class returns the class part of WM_CLASS property
+func (c X11Client) Class() string {
+	classPair, err := icccm.WmClassGet(c.X, c.window.Id)
+	if err != nil || classPair == nil {
+		return ""
+	}
+
+	return classPair.Class
+}
+
+func (c X11Client) WorkspaceNum() uint {
+	return c.workspaceNum
+}
+
+// Urgent reports whether the client demands attention,
+// either by _NET_WM_STATE_DEMANDS_ATTENTION or by urgency hint of WM_HINTS
+func (c X11Client) Urgent() bool {
+	states, err := ewmh.WmStateGet(c.X, c.window.Id)
+	if err == nil && slices.Contains(states, "_NET_WM_STATE_DEMANDS_ATTENTION") {
+		return true
+	}
+
+	hints, err := icccm.WmHintsGet(c.X, c.window.Id)
+	return err == nil && hints.Flags&icccm.HintUrgency != 0
+}
+
 func (c X11Client) String() string {
 	return fmt.Sprintf("'%s' (%#x)", c.name(), uint32(c.id))
 }
 
-func (c X11Client) MoveResize(x, y, width, height int) {
+func (c X11Client) MoveResize(x, y, width, height int) error {
 	c.Unmaximize()
 
 	dw, dh := c.DecorDimensions()
 	err := c.window.WMMoveResize(x, y, width-dw, height-dh)
 
 	if err != nil {
-		log.Info("Error when moving ", c.name(), " ", err)
+		return fmt.Errorf("Failed to move %v: %w", c, err)
+	}
+	return nil
+}
+
+// Geometry returns the current position and size of the client including decorations
+func (c X11Client) Geometry() (x, y, width, height int, err error) {
+	geom, err := c.window.DecorGeometry()
+	if err != nil {
+		return
 	}
+
+	return geom.X(), geom.Y(), geom.Width(), geom.Height(), nil
 }
 
 // DecorDimensions returns the width and height occupied by window decorations
@@ -120,14 +167,17 @@ func (c X11Client) Decorate() {
 }
 
 // Restore resizes and decorates window to pre-tiling state.
-func (c X11Client) Restore() {
+func (c X11Client) Restore() error {
 	c.Decorate()
 	geom := c.savedProp.Geom
 	log.Info("Restoring ", c.name(), ": ", "X: ", geom.X(), " Y: ", geom.Y())
-	c.MoveResize(geom.X(), geom.Y(), geom.Width(), geom.Height())
+	return c.MoveResize(geom.X(), geom.Y(), geom.Width(), geom.Height())
 }
 
 // Activate makes the client the currently active window
-func (c X11Client) Activate() {
-	ewmh.ActiveWindowReq(c.X, c.window.Id)
+func (c X11Client) Activate() error {
+	if err := ewmh.ActiveWindowReq(c.X, c.window.Id); err != nil {
+		return fmt.Errorf("Failed to activate %v: %w", c, err)
+	}
+	return nil
 }
@@ -7,8 +7,12 @@ type ClientId interface {
 
 type Client interface {
 	Id() ClientId
+	Title() string
+	Class() string
+	WorkspaceNum() uint
+	Urgent() bool
 
-	Activate()
+	Activate() error
 
 	Decorate()
 	Undecorate()
@@ -17,8 +21,9 @@ type Client interface {
 	Maximize()
 	Unmaximize()
 
-	MoveResize(x, y, width, height int)
-	Restore()
+	MoveResize(x, y, width, height int) error
+	Geometry() (x, y, width, height int, err error)
+	Restore() error
 
 	String() string
 }
@@ -26,19 +31,26 @@ type Client interface {
 type workspace interface {
 	AddClient(c Client)
 	RemoveClient(c Client)
+	HideClient(c Client)
+	ShowClient(c Client)
 
 	IsTiling() bool
 	Tile()
+	Destroy()
 }
 
 type Tracker[T workspace] interface {
 	ParseClientId(string) (ClientId, error)
 
 	Client(id ClientId) (client Client, exists bool)
+	Clients() []Client
 	ActiveClient() (client Client, exists bool)
+	LastActiveClient() (client Client, exists bool)
 
 	CurentWorkspaceNum() uint
+	LastWorkspaceNum() uint
 	WorkspaceCount() uint
+	WorkspaceName(index uint) string
 	Workspace(index uint) T
 	ActiveWorkspace() T
 
@@ -48,6 +60,39 @@ type Tracker[T workspace] interface {
 	Sync()
 }
 
+// StickyPolicy defines how windows shown on all desktops are handled
+type StickyPolicy string
+
+const (
+	StickyIgnore StickyPolicy = "ignore" // Sticky windows are not tiled
+	StickyFollow StickyPolicy = "follow" // Sticky windows are tiled in the current workspace
+)
+
+// TransientPolicy defines how transient windows such as dialogs are handled
+type TransientPolicy string
+
+const (
+	TransientIgnore TransientPolicy = "ignore" // Transient windows are left where they are
+	TransientCenter TransientPolicy = "center" // Transient windows are centered over their parent
+)
+
+// Events reported by the tracker
+type TrackerEvent string
+
+const (
+	WorkspaceChanged TrackerEvent = "workspace_changed" // Args: workspace number
+	WindowAdded      TrackerEvent = "window_added"      // Args: client id, workspace number
+	WindowRemoved    TrackerEvent = "window_removed"    // Args: client id
+	WindowFocused    TrackerEvent = "window_focused"    // Args: window id
+)
+
+type TrackerOptions struct {
+	ClassesToIgnore []string
+	StickyPolicy    StickyPolicy
+	TransientPolicy TransientPolicy
+	OnEvent         func(event TrackerEvent, args ...string) // Optional
+}
+
 type Keybinder interface {
 	Bind(keyStr string, callback func())
 }
@@ -1,7 +1,9 @@
 package backend
 
 import (
+	"maps"
 	"slices"
+	"strconv"
 	"strings"
 
 	"github.com/jezek/xgb/xproto"
@@ -15,16 +17,27 @@ import (
 	log "github.com/sirupsen/logrus"
 )
 
+// Value of _NET_WM_DESKTOP for windows shown on all desktops
+const allDesktops = 0xFFFFFFFF
+
 type X11Tracker[T workspace] struct {
 	X               *xgbutil.XUtil
 	classesToIgnore []string
-
-	clients      map[xproto.Window]X11Client
-	activeClient xproto.Window // Current Active window
-
-	currentWorkpaceNum uint // Current Desktop
-	workspaceCount     uint // Number of desktop workspaces.
+	stickyPolicy    StickyPolicy
+	transientPolicy TransientPolicy
+	onEvent         func(event TrackerEvent, args ...string)
+
+	clients          map[xproto.Window]*X11Client
+	activeClient     xproto.Window              // Current Active window
+	lastActiveClient xproto.Window              // Window active before the current one
+	transients       map[xproto.Window]struct{} // Transient windows already handled
+
+	currentWorkpaceNum uint     // Current Desktop
+	lastWorkspaceNum   uint     // Desktop visited before the current one
+	workspaceCount     uint     // Number of desktop workspaces.
+	workspaceNames     []string // Names of desktops, could be shorter than the count
 	workspaces         map[uint]T
+	workspaceFactory   WorkspaceFactory[T]
 
 	workArea []ewmh.Workarea
 }
@@ -36,7 +49,7 @@ type Workarea struct {
 
 type WorkspaceFactory[T workspace] func(tracker Tracker[T], num uint) T
 
-func newX11Tracker[WorkspaceT workspace](backend x11Backend, classesToIgnore []string, workspaceFactory WorkspaceFactory[WorkspaceT]) (*X11Tracker[WorkspaceT], error) {
+func newX11Tracker[WorkspaceT workspace](backend x11Backend, options TrackerOptions, workspaceFactory WorkspaceFactory[WorkspaceT]) (*X11Tracker[WorkspaceT], error) {
 	X := backend.X
 
 	workspaceCount, err := ewmh.NumberOfDesktopsGet(X)
@@ -60,16 +73,29 @@ func newX11Tracker[WorkspaceT workspace](backend x11Backend, classesToIgnore []s
 		return nil, err
 	}
 
+	workspaceNames, err := ewmh.DesktopNamesGet(X)
+	if err != nil {
+		// Desktops are not required to have names
+		log.Info(err)
+	}
+
 	tracker := X11Tracker[WorkspaceT]{
 		X:               X,
-		classesToIgnore: classesToIgnore,
+		classesToIgnore: options.ClassesToIgnore,
+		stickyPolicy:    options.StickyPolicy,
+		transientPolicy: options.TransientPolicy,
+		onEvent:         options.OnEvent,
 
-		clients:    make(map[xproto.Window]X11Client),
+		clients:    make(map[xproto.Window]*X11Client),
+		transients: make(map[xproto.Window]struct{}),
 		workspaces: make(map[uint]WorkspaceT),
 
+		workspaceFactory:   workspaceFactory,
 		workspaceCount:     workspaceCount,
+		workspaceNames:     workspaceNames,
 		activeClient:       activeWin,
 		currentWorkpaceNum: currentWorkspace,
+		lastWorkspaceNum:   currentWorkspace,
 		workArea:           workArea,
 	}
 
@@ -95,23 +121,63 @@ func (tr *X11Tracker[T]) ParseClientId(str string) (ClientId, error) {
 func (tr *X11Tracker[T]) Client(id ClientId) (client Client, exists bool) {
 	xid := id.(X11ClientId) // Catch fire and explode if ClientId is not X11ClientId
 
-	client, exists = tr.clients[xproto.Window(xid)]
-	return client, exists
+	c, exists := tr.clients[xproto.Window(xid)]
+	if !exists {
+		return nil, false
+	}
+	return c, true
+}
+
+// Clients returns all tracked clients ordered by id
+func (tr *X11Tracker[T]) Clients() []Client {
+	wids := slices.Sorted(maps.Keys(tr.clients))
+
+	clients := make([]Client, len(wids))
+	for i, wid := range wids {
+		clients[i] = tr.clients[wid]
+	}
+	return clients
 }
 
 func (tr *X11Tracker[T]) ActiveClient() (client Client, exists bool) {
-	client, exists = tr.clients[tr.activeClient]
-	return client, exists
+	c, exists := tr.clients[tr.activeClient]
+	if !exists {
+		return nil, false
+	}
+	return c, true
+}
+
+// LastActiveClient returns the client that was active before the current one, if it is still tracked
+func (tr *X11Tracker[T]) LastActiveClient() (client Client, exists bool) {
+	c, exists := tr.clients[tr.lastActiveClient]
+	if !exists {
+		return nil, false
+	}
+	return c, true
 }
 
 func (tr *X11Tracker[T]) CurentWorkspaceNum() uint {
 	return tr.currentWorkpaceNum
 }
 
+// LastWorkspaceNum returns the desktop visited before the current one,
+// or the current one if the desktop was not changed yet
+func (tr *X11Tracker[T]) LastWorkspaceNum() uint {
+	return tr.lastWorkspaceNum
+}
+
 func (tr *X11Tracker[T]) WorkspaceCount() uint {
 	return tr.workspaceCount
 }
 
+// WorkspaceName returns the name of the desktop or empty string if it has no name
+func (tr *X11Tracker[T]) WorkspaceName(index uint) string {
+	if index >= uint(len(tr.workspaceNames)) {
+		return ""
+	}
+	return tr.workspaceNames[index]
+}
+
 func (tr *X11Tracker[T]) Workspace(index uint) T {
 	return tr.workspaces[index]
 }
@@ -122,6 +188,14 @@ func (tr *X11Tracker[T]) ActiveWorkspace() T {
 
 // WorkAreaDimensions returns the dimension of the requested workspace.
 func (tr *X11Tracker[T]) WorkAreaDimensions(num uint) (x, y, width, height int) {
+	if len(tr.workArea) == 0 {
+		return
+	}
+	// Some window managers set the same work area for all desktops only once
+	if num >= uint(len(tr.workArea)) {
+		num = 0
+	}
+
 	w := tr.workArea[num]
 	return w.X, w.Y, int(w.Width), int(w.Height)
 }
@@ -137,11 +211,30 @@ func (tr *X11Tracker[T]) onPropertyNotify(X *xgbutil.XUtil, e xevent.PropertyNot
 	aname, _ := xprop.AtomName(X, e.Atom)
 	switch {
 	case aname == "_NET_ACTIVE_WINDOW":
+		previousActiveClient := tr.activeClient
 		tr.activeClient, err = ewmh.ActiveWindowGet(X)
+		if err == nil && tr.activeClient != previousActiveClient {
+			if _, tracked := tr.clients[previousActiveClient]; tracked {
+				tr.lastActiveClient = previousActiveClient
+			}
+			tr.emit(WindowFocused, newX11ClientIdFromWid(tr.activeClient).String())
+		}
 	case aname == "_NET_CURRENT_DESKTOP":
+		previousWorkspaceNum := tr.currentWorkpaceNum
 		tr.currentWorkpaceNum, err = ewmh.CurrentDesktopGet(X)
+		if err == nil && tr.currentWorkpaceNum != previousWorkspaceNum {
+			tr.lastWorkspaceNum = previousWorkspaceNum
+			tr.emit(WorkspaceChanged, strconv.FormatUint(uint64(tr.currentWorkpaceNum), 10))
+		}
+		tr.updateStickyClients()
 	case aname == "_NET_NUMBER_OF_DESKTOPS":
-		tr.workspaceCount, err = ewmh.NumberOfDesktopsGet(X)
+		var workspaceCount uint
+		workspaceCount, err = ewmh.NumberOfDesktopsGet(X)
+		if err == nil {
+			tr.updateWorkspaceCount(workspaceCount)
+		}
+	case aname == "_NET_DESKTOP_NAMES":
+		tr.workspaceNames, err = ewmh.DesktopNamesGet(X)
 	case aname == "_NET_WORKAREA":
 		tr.workArea, err = ewmh.WorkareaGet(X)
 	case aname == "_NET_CLIENT_LIST_STACKING":
@@ -155,17 +248,79 @@ func (tr *X11Tracker[T]) onPropertyNotify(X *xgbutil.XUtil, e xevent.PropertyNot
 	}
 }
 
+// updateWorkspaceCount creates workspaces for the added desktops
+// and moves clients from the removed desktops to the last remaining one.
+func (tr *X11Tracker[T]) updateWorkspaceCount(count uint) {
+	oldCount := tr.workspaceCount
+	if count == 0 || count == oldCount {
+		return
+	}
+	tr.workspaceCount = count
+
+	for num := oldCount; num < count; num++ {
+		tr.workspaces[num] = tr.workspaceFactory(tr, num)
+	}
+
+	if count > oldCount {
+		return
+	}
+
+	// Stop the animations of the removed workspaces, so they do not move the clients taken over by the last one
+	for num := count; num < oldCount; num++ {
+		tr.workspaces[num].Destroy()
+	}
+
+	lastNum := count - 1
+	lastWs := tr.workspaces[lastNum]
+	if tr.lastWorkspaceNum >= count {
+		tr.lastWorkspaceNum = lastNum
+	}
+	for _, c := range tr.clients {
+		if c.workspaceNum < count {
+			continue
+		}
+
+		oldWs := tr.workspaces[c.workspaceNum]
+		oldWs.RemoveClient(c)
+		c.workspaceNum = lastNum
+
+		if tr.isClientTileable(c) {
+			lastWs.AddClient(c)
+			if oldWs.IsTiling() && !lastWs.IsTiling() {
+				if err := c.Restore(); err != nil {
+					log.Warn(err)
+				}
+			}
+		}
+	}
+
+	for num := count; num < oldCount; num++ {
+		delete(tr.workspaces, num)
+	}
+	if tr.currentWorkpaceNum >= count {
+		tr.currentWorkpaceNum = lastNum
+	}
+
+	lastWs.Tile()
+}
+
 // updateClients updates the list of tracked clients with the most up to date list of clients.
 func (tr *X11Tracker[T]) updateClients() {
 	clientList, _ := ewmh.ClientListStackingGet(tr.X)
 
 	for _, wid := range clientList {
-		if !tr.isWindowNormal(wid) || tr.isWindowHidden(wid) ||
+		if !tr.isWindowNormal(wid) ||
 			!tr.isWindowResizable(wid) ||
 			tr.shouldIgnore(wid) {
 			continue
 		}
 
+		// Dialogs and the like are never tiled, so the parent keeps its slot
+		if tr.isWindowTransient(wid) {
+			tr.handleTransientWindow(wid)
+			continue
+		}
+
 		tr.startTrackingWindow(wid)
 	}
 
@@ -178,16 +333,17 @@ func (tr *X11Tracker[T]) updateClients() {
 		}
 	}
 
+	for transientWid := range tr.transients {
+		if !slices.Contains(clientList, transientWid) {
+			delete(tr.transients, transientWid)
+		}
+	}
+
 }
 
-func (tr *X11Tracker[T]) newClient(wid xproto.Window) X11Client {
+func (tr *X11Tracker[T]) newClient(wid xproto.Window) *X11Client {
 	win := xwindow.New(tr.X, wid)
 
-	workspaceNum, err := ewmh.WmDesktopGet(tr.X, wid)
-	if err != nil {
-		workspaceNum = tr.currentWorkpaceNum
-	}
-
 	savedGeom, err := win.DecorGeometry()
 	if err != nil {
 		log.Info(err)
@@ -201,10 +357,10 @@ func (tr *X11Tracker[T]) newClient(wid xproto.Window) X11Client {
 		hasDecoration = motif.Decor(mh)
 	}
 
-	return X11Client{
+	c := &X11Client{
 		id:           newX11ClientIdFromWid(wid),
 		window:       win,
-		workspaceNum: workspaceNum,
+		workspaceNum: tr.currentWorkpaceNum,
 		savedProp: Prop{
 			Geom:       savedGeom,
 			decoration: hasDecoration,
@@ -212,6 +368,37 @@ func (tr *X11Tracker[T]) newClient(wid xproto.Window) X11Client {
 
 		X: tr.X,
 	}
+	tr.readClientState(c)
+
+	return c
+}
+
+// readClientState updates the client state and workspace from its EWMH properties
+func (tr *X11Tracker[T]) readClientState(c *X11Client) {
+	states, _ := ewmh.WmStateGet(tr.X, c.window.Id)
+	desktop, err := ewmh.WmDesktopGet(tr.X, c.window.Id)
+
+	c.minimized = slices.Contains(states, "_NET_WM_STATE_HIDDEN")
+	c.fullscreen = slices.Contains(states, "_NET_WM_STATE_FULLSCREEN")
+	c.above = slices.Contains(states, "_NET_WM_STATE_ABOVE")
+	c.sticky = (err == nil && desktop == allDesktops) ||
+		slices.Contains(states, "_NET_WM_STATE_STICKY")
+
+	switch {
+	case c.sticky && tr.stickyPolicy == StickyFollow:
+		c.workspaceNum = tr.currentWorkpaceNum
+	case err == nil && desktop < tr.workspaceCount:
+		c.workspaceNum = desktop
+	}
+}
+
+// isClientTileable returns true if the client should be placed by the layouts
+func (tr *X11Tracker[T]) isClientTileable(c *X11Client) bool {
+	if c.minimized || c.fullscreen || c.above {
+		return false
+	}
+
+	return !c.sticky || tr.stickyPolicy == StickyFollow
 }
 
 func (tr *X11Tracker[T]) isWindowNormal(w xproto.Window) bool {
@@ -228,10 +415,10 @@ func (tr *X11Tracker[T]) isWindowNormal(w xproto.Window) bool {
 	return true
 }
 
-// isWindowHidden returns true if the window has been minimized.
-func (tr *X11Tracker[T]) isWindowHidden(w xproto.Window) bool {
-	states, _ := ewmh.WmStateGet(tr.X, w)
-	return slices.Contains(states, "_NET_WM_STATE_HIDDEN")
+// isWindowTransient returns true if the window is transient for another window, e.g. a dialog
+func (tr *X11Tracker[T]) isWindowTransient(w xproto.Window) bool {
+	parent, err := icccm.WmTransientForGet(tr.X, w)
+	return err == nil && parent != 0
 }
 
 func (tr *X11Tracker[T]) isWindowResizable(w xproto.Window) bool {
@@ -274,6 +461,42 @@ func (tr *X11Tracker[T]) shouldIgnore(w xproto.Window) bool {
 
 /* Client tracking */
 
+// handleTransientWindow places the transient window according to the transient policy.
+// Each transient window is handled only once, so it can be freely moved afterwards.
+func (tr *X11Tracker[T]) handleTransientWindow(wid xproto.Window) {
+	if _, handled := tr.transients[wid]; handled {
+		return
+	}
+	tr.transients[wid] = struct{}{}
+
+	if tr.transientPolicy != TransientCenter {
+		return
+	}
+
+	win := xwindow.New(tr.X, wid)
+	geom, err := win.DecorGeometry()
+	if err != nil {
+		log.Info(err)
+		return
+	}
+
+	// Center over the parent or the work area if the parent is not known
+	x, y, width, height := tr.WorkAreaDimensions(tr.currentWorkpaceNum)
+	parent, err := icccm.WmTransientForGet(tr.X, wid)
+	if err == nil && parent != tr.X.RootWin() {
+		parentGeom, err := xwindow.New(tr.X, parent).DecorGeometry()
+		if err == nil {
+			x, y = parentGeom.X(), parentGeom.Y()
+			width, height = parentGeom.Width(), parentGeom.Height()
+		}
+	}
+
+	err = win.WMMove(x+(width-geom.Width())/2, y+(height-geom.Height())/2)
+	if err != nil {
+		log.Info("Error when centering transient window ", wid, " ", err)
+	}
+}
+
 func (tr *X11Tracker[T]) IsTracked(wid xproto.Window) bool {
 	_, tracked := tr.clients[wid]
 	return tracked
@@ -286,15 +509,20 @@ func (tr *X11Tracker[T]) startTrackingWindow(wid xproto.Window) {
 	}
 
 	c := tr.newClient(wid)
-	if c.workspaceNum > tr.workspaceCount {
+	if c.workspaceNum >= tr.workspaceCount {
 		return
 	}
-	tr.attachHandlers(&c)
+	tr.attachHandlers(c)
 
 	tr.clients[c.window.Id] = c
-	ws := tr.workspaces[c.workspaceNum]
-	ws.AddClient(c)
+	tr.emit(WindowAdded, c.id.String(), strconv.FormatUint(uint64(c.workspaceNum), 10))
 
+	// Minimized, fullscreen and the like clients are tracked,
+	// but get into layouts only when they become tileable
+	if tr.isClientTileable(c) {
+		ws := tr.workspaces[c.workspaceNum]
+		ws.AddClient(c)
+	}
 }
 
 func (tr *X11Tracker[T]) stopTrackingWindow(wid xproto.Window) {
@@ -304,6 +532,13 @@ func (tr *X11Tracker[T]) stopTrackingWindow(wid xproto.Window) {
 		ws.RemoveClient(c)
 		xevent.Detach(tr.X, wid)
 		delete(tr.clients, wid)
+		tr.emit(WindowRemoved, c.id.String())
+	}
+}
+
+func (tr *X11Tracker[T]) emit(event TrackerEvent, args ...string) {
+	if tr.onEvent != nil {
+		tr.onEvent(event, args...)
 	}
 }
 
@@ -313,44 +548,68 @@ func (tr *X11Tracker[T]) attachHandlers(c *X11Client) {
 	c.window.Listen(xproto.EventMaskPropertyChange)
 
 	xevent.PropertyNotifyFun(func(x *xgbutil.XUtil, ev xevent.PropertyNotifyEvent) {
-		if aname, _ := xprop.AtomName(tr.X, ev.Atom); aname == "_NET_WM_STATE" {
-			tr.handleMinimizedClient(c)
-		}
-	}).Connect(tr.X, c.window.Id)
-
-	xevent.PropertyNotifyFun(func(x *xgbutil.XUtil, ev xevent.PropertyNotifyEvent) {
-		if aname, _ := xprop.AtomName(tr.X, ev.Atom); aname == "_NET_WM_DESKTOP" {
-			tr.handleDesktopChange(c)
+		aname, _ := xprop.AtomName(tr.X, ev.Atom)
+		if aname == "_NET_WM_STATE" || aname == "_NET_WM_DESKTOP" {
+			tr.handleStateChange(c)
 		}
 	}).Connect(tr.X, c.window.Id)
 }
 
-func (tr *X11Tracker[T]) handleMinimizedClient(c *X11Client) {
-	states, _ := ewmh.WmStateGet(tr.X, c.window.Id)
-	for _, state := range states {
-		if state == "_NET_WM_STATE_HIDDEN" {
-			tr.workspaces[c.workspaceNum].RemoveClient(*c)
-			tr.stopTrackingWindow(c.window.Id)
-			tr.workspaces[c.workspaceNum].Tile()
+// handleStateChange keeps layouts in sync with the client state.
+// Clients that stop being tileable (e.g. minimized or fullscreen) are
+// taken out of the layouts and put back to the same slot later,
+// clients moved to another workspace are moved between the layouts.
+func (tr *X11Tracker[T]) handleStateChange(c *X11Client) {
+	oldWorkspaceNum := c.workspaceNum
+	wasTileable := tr.isClientTileable(c)
+
+	tr.readClientState(c)
+
+	newWorkspaceNum := c.workspaceNum
+	isTileable := tr.isClientTileable(c)
+
+	if oldWorkspaceNum == newWorkspaceNum {
+		ws := tr.workspaces[newWorkspaceNum]
+		switch {
+		case wasTileable && !isTileable:
+			ws.HideClient(c)
+		case !wasTileable && isTileable:
+			ws.ShowClient(c)
+		default:
+			return
 		}
+		ws.Tile()
+		return
 	}
-}
-
-func (tr *X11Tracker[T]) handleDesktopChange(c *X11Client) {
-	newWorkspaceNum, _ := ewmh.WmDesktopGet(tr.X, c.window.Id)
-	oldWorkspaceNum := c.workspaceNum
 
-	tr.workspaces[oldWorkspaceNum].RemoveClient(*c)
-	tr.workspaces[newWorkspaceNum].AddClient(*c)
+	tr.workspaces[oldWorkspaceNum].RemoveClient(c)
+	if isTileable {
+		tr.workspaces[newWorkspaceNum].AddClient(c)
+	}
 
-	c.workspaceNum = newWorkspaceNum
 	if tr.workspaces[oldWorkspaceNum].IsTiling() {
 		tr.workspaces[oldWorkspaceNum].Tile()
 	}
 
 	if tr.workspaces[newWorkspaceNum].IsTiling() {
 		tr.workspaces[newWorkspaceNum].Tile()
-	} else {
-		c.Restore()
+	} else if isTileable {
+		if err := c.Restore(); err != nil {
+			log.Warn(err)
+		}
+	}
+}
+
+// updateStickyClients moves sticky clients to the current workspace
+// if they are tiled according to the sticky policy
+func (tr *X11Tracker[T]) updateStickyClients() {
+	if tr.stickyPolicy != StickyFollow {
+		return
+	}
+
+	for _, c := range tr.clients {
+		if c.sticky {
+			tr.handleStateChange(c)
+		}
 	}
 }
@@ -30,3 +30,28 @@ func requestStartNewCommandSequence(commandChan chan<- CommandRequest) {
 	commandChan <- request
 	_ = <-replyChan
 }
+
+var endCommandSequenceCommand = types.Command{
+	Kind: types.Action,
+	Name: "__end_command_sequence",
+	Args: nil,
+}
+
+// Returns the error of applying the layout changes
+func requestEndCommandSequence(commandChan chan<- CommandRequest) error {
+	request, replyChan := NewCommandRequest(endCommandSequenceCommand)
+	commandChan <- request
+	return (<-replyChan).Err
+}
+
+var abortCommandSequenceCommand = types.Command{
+	Kind: types.Action,
+	Name: "__abort_command_sequence",
+	Args: nil,
+}
+
+func requestAbortCommandSequence(commandChan chan<- CommandRequest) {
+	request, replyChan := NewCommandRequest(abortCommandSequenceCommand)
+	commandChan <- request
+	_ = <-replyChan
+}
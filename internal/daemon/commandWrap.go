@@ -1,13 +1,28 @@
 package daemon
 
-import "fmt"
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	commandparser "github.com/Alnivel/zentile/internal/command_parser"
+	"github.com/Alnivel/zentile/internal/types"
+)
 
 type commandFunc func(...string) ([]string, error)
 
+// recordFunc is the command function for queries returning structured results
+type recordFunc func(...string) ([]types.Record, error)
+
 type CommandWrap struct {
-	minIn int
-	maxIn int
-	fn    commandFunc
+	minIn       int
+	maxIn       int
+	args        []types.ArgSpec // Arguments after the first minIn ones are optional
+	usage       string          // Used instead of the one made of args if set
+	block       bool            // The last argument is a block of commands following the optional ones
+	description string
+	fn          commandFunc
+	recordFn    recordFunc // Used instead of fn if set
 }
 
 func (command CommandWrap) MinIn() int {
@@ -17,6 +32,42 @@ func (command CommandWrap) MaxIn() int {
 	return command.maxIn
 }
 
+func (command CommandWrap) Args() []types.ArgSpec {
+	return command.args
+}
+
+// Specs of the count provided arguments.
+// Optional arguments are the trailing ones, except for the block, which is always the last.
+func (command CommandWrap) ArgSpecs(count int) []types.ArgSpec {
+	if !command.block || count >= len(command.args) || count == 0 {
+		return command.args[:min(count, len(command.args))]
+	}
+
+	specs := slices.Clone(command.args[:count-1])
+	return append(specs, command.args[len(command.args)-1])
+}
+
+func (command CommandWrap) Description() string {
+	return command.description
+}
+
+// Usage of the command arguments, e.g. "NAME [WID]"
+func (command CommandWrap) Usage() string {
+	if command.usage != "" {
+		return command.usage
+	}
+
+	usage := make([]string, len(command.args))
+	for i, arg := range command.args {
+		if i >= command.minIn {
+			usage[i] = "[" + arg.Name + "]"
+		} else {
+			usage[i] = arg.Name
+		}
+	}
+	return strings.Join(usage, " ")
+}
+
 func (command CommandWrap) ValidateArgCount(count int) error {
 	if count >= command.minIn && count <= command.maxIn {
 		return nil
@@ -35,10 +86,23 @@ func (command CommandWrap) ValidateArgCount(count int) error {
 	}
 }
 
-func (command CommandWrap) Call(s ...string) ([]string, error) {
+func (command CommandWrap) Call(s ...string) types.CommandResult {
 	if err := command.ValidateArgCount(len(s)); err != nil {
-		return nil, err
-	} else {
-		return command.fn(s...)
+		return types.CommandResult{Messages: nil, Err: err}
+	}
+	if err := commandparser.ValidateArgs(command.ArgSpecs(len(s)), s); err != nil {
+		return types.CommandResult{Messages: nil, Err: err}
+	}
+
+	if command.recordFn == nil {
+		messages, err := command.fn(s...)
+		return types.CommandResult{Messages: messages, Err: err}
+	}
+
+	records, err := command.recordFn(s...)
+	messages := make([]string, len(records))
+	for i, record := range records {
+		messages[i] = record.String()
 	}
+	return types.CommandResult{Messages: messages, Records: records, Err: err}
 }
@@ -3,9 +3,12 @@ package daemon
 import (
 	"errors"
 	"fmt"
+	"maps"
+	"slices"
 	"strconv"
 	"strings"
 
+	commandparser "github.com/Alnivel/zentile/internal/command_parser"
 	"github.com/Alnivel/zentile/internal/config"
 	"github.com/Alnivel/zentile/internal/types"
 )
@@ -15,32 +18,47 @@ var (
 	CommandNotExists      = errors.New("Command do not exists")
 	IncorrectNumberOfArgs = errors.New("Incorrect number of arguments")
 	NoWindowInWorkspace   = errors.New("No target window found in target workspace")
+	NoActiveWindow        = errors.New("No active window")
+	NoTargetWindow        = errors.New("No target window")
+	WindowNotTracked      = errors.New("Window is not tracked")
 )
 
+// Layout names accepted by set layout, none untiles the workspace
+var layoutNames = append(slices.Clone(config.LayoutNames), "none")
+
+// Every layout can be listed once by set layouts
+var maxLayouts = len(config.LayoutNames)
+
 type CommandMap map[string]CommandWrap
 
 type Commands struct {
-	Actions CommandMap
-	Setters CommandMap
-	Queries CommandMap
-	Fors    CommandMap
+	Actions  CommandMap
+	Setters  CommandMap
+	Queries  CommandMap
+	Fors     CommandMap
+	Foreachs CommandMap
+
+	marks *Marks
+	tx    *Transaction
 }
 
 type CommandContext struct {
 	TargetClient       Client
 	TargetWorkspaceNum uint
-	Variables          map[string]Client
+	Variables          map[string]Client // Reset for each command sequence
+	Marks              *Marks            // Kept across command sequences
 }
 
 var defaultCtx CommandContext
 
-func InitCommands(tracker Tracker, config *config.Config) Commands {
+// Tracker and transaction can be nil if the commands are only parsed, not run
+func InitCommands(tracker Tracker, tx *Transaction, config *config.Config) Commands {
 	var ctx *CommandContext = &defaultCtx
+	ctx.Variables = make(map[string]Client)
+	ctx.Marks = NewMarks()
 
 	if tracker != nil {
-		if client, exists := tracker.ActiveClient(); exists {
-			defaultCtx.TargetClient = client
-		}
+		defaultCtx.TargetClient, _ = tracker.ActiveClient()
 		defaultCtx.TargetWorkspaceNum = tracker.CurentWorkspaceNum()
 	} else {
 		// It's okay for it to be nil,
@@ -48,48 +66,80 @@ func InitCommands(tracker Tracker, config *config.Config) Commands {
 		// at the end of this function
 	}
 
-	keybindActions := map[string]func(){
-		"tile": func() {
+	// Workspace with the number if it is tiling, the layout dependent commands fail otherwise
+	tilingWorkspace := func(num uint) (*Workspace, error) {
+		ws := tracker.Workspace(num)
+		if !ws.IsTiling() {
+			return nil, NotTiling
+		}
+		return ws, nil
+	}
+
+	keybindActions := map[string]func() error{
+		"tile": func() error {
 			ws := tracker.Workspace(ctx.TargetWorkspaceNum)
-			ws.isTiling = true
+			ws.setTiling(true)
 			ws.Tile()
+			return nil
 		},
-		"untile": func() {
+		"untile": func() error {
 			ws := tracker.Workspace(ctx.TargetWorkspaceNum)
 			ws.Untile()
+			return nil
 		},
-		"make_active_window_master": func() {
-			ws := tracker.ActiveWorkspace()
+		"make_active_window_master": func() error {
+			ws, err := tilingWorkspace(tracker.CurentWorkspaceNum())
+			if err != nil {
+				return err
+			}
 			client, exists := tracker.ActiveClient()
 			if exists {
 				ws.ActiveLayout().MakeMaster(client)
 				ws.Tile()
 			}
+			return nil
 		},
-		"switch_layout": func() {
+		"switch_layout": func() error {
 			tracker.Workspace(ctx.TargetWorkspaceNum).SwitchLayout()
+			return nil
 		},
-		"increase_master": func() {
-			ws := tracker.Workspace(ctx.TargetWorkspaceNum)
+		"increase_master": func() error {
+			ws, err := tilingWorkspace(ctx.TargetWorkspaceNum)
+			if err != nil {
+				return err
+			}
 			ws.ActiveLayout().IncMaster()
 			ws.Tile()
+			return nil
 		},
-		"decrease_master": func() {
-			ws := tracker.Workspace(ctx.TargetWorkspaceNum)
+		"decrease_master": func() error {
+			ws, err := tilingWorkspace(ctx.TargetWorkspaceNum)
+			if err != nil {
+				return err
+			}
 			ws.ActiveLayout().DecreaseMaster()
 			ws.Tile()
+			return nil
 		},
-		"increment_master": func() {
-			ws := tracker.Workspace(ctx.TargetWorkspaceNum)
+		"increment_master": func() error {
+			ws, err := tilingWorkspace(ctx.TargetWorkspaceNum)
+			if err != nil {
+				return err
+			}
 			layout := ws.ActiveLayout()
 			layout.SetProportion(layout.GetProportion() + config.ProportionStep)
 			ws.Tile()
+			return nil
 		},
-		"decrement_master": func() {
-			ws := tracker.Workspace(ctx.TargetWorkspaceNum)
+		"decrement_master": func() error {
+			ws, err := tilingWorkspace(ctx.TargetWorkspaceNum)
+			if err != nil {
+				return err
+			}
 			layout := ws.ActiveLayout()
 			layout.SetProportion(layout.GetProportion() - config.ProportionStep)
 			ws.Tile()
+			return nil
 		},
 	}
 
@@ -97,60 +147,138 @@ func InitCommands(tracker Tracker, config *config.Config) Commands {
 		// Internal command, used for resetting context on each new command sequence
 		"__start_new_command_sequence": CommandWrap{
 			minIn: 0, maxIn: 0,
+			description: "Reset the targets and variables for a new command sequence",
 			fn: func(args ...string) ([]string, error) {
-				if client, exists := tracker.ActiveClient(); exists {
-					ctx.TargetClient = client
-				}
+				ctx.TargetClient, _ = tracker.ActiveClient()
 				ctx.TargetWorkspaceNum = tracker.CurentWorkspaceNum()
+				ctx.Variables = make(map[string]Client)
+				tx.Begin(tracker)
+				return nil, nil
+			},
+		},
+		// Internal command, used for applying the layout changes at the end of command sequence
+		"__end_command_sequence": CommandWrap{
+			minIn: 0, maxIn: 0,
+			description: "Apply the layout changes made by the command sequence",
+			fn: func(args ...string) ([]string, error) {
+				return nil, tx.Commit()
+			},
+		},
+		// Internal command, used for rolling back the layout changes of unfinished command sequence
+		"__abort_command_sequence": CommandWrap{
+			minIn: 0, maxIn: 0,
+			description: "Roll back the layout changes made by the command sequence",
+			fn: func(args ...string) ([]string, error) {
+				tx.Rollback()
+				return nil, nil
+			},
+		},
+		"mark": CommandWrap{
+			minIn: 1, maxIn: 2,
+			args: []types.ArgSpec{
+				{Name: "NAME", Type: types.ArgMark},
+				{Name: "WID", Type: types.ArgWindow},
+			},
+			description: "Mark the target window, or the window WID, with NAME",
+			fn: func(args ...string) ([]string, error) {
+				name, err := parseMarkName(args[0])
+				if err != nil {
+					return nil, err
+				}
+
+				client := ctx.TargetClient
+				if len(args) == 2 {
+					client, err = parseClient(args[1], ctx, tracker)
+					if err != nil {
+						return nil, err
+					}
+				}
+				if client == nil {
+					return nil, NoTargetWindow
+				}
+
+				ctx.Marks.Set(name, client)
 				return nil, nil
 			},
 		},
+		"unmark": CommandWrap{
+			minIn: 1, maxIn: 1,
+			args: []types.ArgSpec{
+				{Name: "NAME", Type: types.ArgMark},
+			},
+			description: "Remove the mark NAME",
+			fn: func(args ...string) ([]string, error) {
+				name, err := parseMarkName(args[0])
+				if err != nil {
+					return nil, err
+				}
+
+				return nil, ctx.Marks.Remove(name)
+			},
+		},
 		"next_window": CommandWrap{
 			minIn: 0, maxIn: 1,
+			args: []types.ArgSpec{
+				{Name: "OFFSET", Type: types.ArgInt},
+			},
+			description: "Focus the next window, or the one OFFSET windows away",
 			fn: func(args ...string) ([]string, error) {
 				offset := 1
 
 				if len(args) == 1 {
-					parsedOffset, err := strconv.ParseInt(args[0], 10, 64)
+					var err error
+					offset, err = commandparser.ParseInt("OFFSET", args[0])
 					if err != nil {
-						return nil, fmt.Errorf("Parse error for offset \"%v\": %w", args[0], err)
+						return nil, err
 					}
-					offset = int(parsedOffset)
 				}
 
-				ws := tracker.Workspace(ctx.TargetWorkspaceNum)
+				ws, err := tilingWorkspace(ctx.TargetWorkspaceNum)
+				if err != nil {
+					return nil, err
+				}
 				nextClient, found := ws.ActiveLayout().ClientRelative(ctx.TargetClient, offset)
 				if !found {
 					return nil, NoWindowInWorkspace
 				}
-				nextClient.Activate()
-				return nil, nil
+				return nil, backendError(nextClient.Activate())
 			},
 		},
 		"previous_window": CommandWrap{
 			minIn: 0, maxIn: 1,
+			args: []types.ArgSpec{
+				{Name: "OFFSET", Type: types.ArgInt},
+			},
+			description: "Focus the previous window, or the one OFFSET windows away",
 			fn: func(args ...string) ([]string, error) {
 				offset := 1
 
 				if len(args) == 1 {
-					parsedOffset, err := strconv.ParseInt(args[0], 10, 64)
+					var err error
+					offset, err = commandparser.ParseInt("OFFSET", args[0])
 					if err != nil {
-						return nil, fmt.Errorf("Parse error for offset \"%v\": %w", args[0], err)
+						return nil, err
 					}
-					offset = int(parsedOffset)
 				}
 
-				ws := tracker.Workspace(ctx.TargetWorkspaceNum)
+				ws, err := tilingWorkspace(ctx.TargetWorkspaceNum)
+				if err != nil {
+					return nil, err
+				}
 				prevClient, found := ws.ActiveLayout().ClientRelative(ctx.TargetClient, -offset)
 				if !found {
 					return nil, NoWindowInWorkspace
 				}
-				prevClient.Activate()
-				return nil, nil
+				return nil, backendError(prevClient.Activate())
 			},
 		},
 		"swap": CommandWrap{
 			minIn: 1, maxIn: 2,
+			args: []types.ArgSpec{
+				{Name: "WID_A", Type: types.ArgWindow},
+				{Name: "WID_B", Type: types.ArgWindow},
+			},
+			description: "Swap locations of the windows in the target layout, the target window is used if only one WID provided",
 			fn: func(args ...string) ([]string, error) {
 				var secondClient, firstClient Client
 				var secondIdErr, firstIdErr error
@@ -159,6 +287,9 @@ func InitCommands(tracker Tracker, config *config.Config) Commands {
 
 				if len(args) == 1 {
 					secondClient = ctx.TargetClient
+					if secondClient == nil {
+						secondIdErr = NoTargetWindow
+					}
 				} else {
 					secondClient, secondIdErr = parseClient(args[1], ctx, tracker)
 				}
@@ -167,12 +298,15 @@ func InitCommands(tracker Tracker, config *config.Config) Commands {
 					return nil, err
 				}
 
-				ws := tracker.Workspace(ctx.TargetWorkspaceNum)
+				ws, err := tilingWorkspace(ctx.TargetWorkspaceNum)
+				if err != nil {
+					return nil, err
+				}
 				success := ws.ActiveLayout().Swap(secondClient, firstClient)
 				if !success {
 					return nil, fmt.Errorf(
-						"Cliend id %v was not found in current workspace",
-						args[0],
+						"%w: the windows are not in the target workspace",
+						WindowNotFound,
 					)
 				}
 
@@ -183,16 +317,32 @@ func InitCommands(tracker Tracker, config *config.Config) Commands {
 		},
 	}
 
+	keybindDescriptions := map[string]string{
+		"tile":                      "Enable tiling for the target workspace",
+		"untile":                    "Disable tiling for the target workspace",
+		"make_active_window_master": "Make the active window master",
+		"switch_layout":             "Cycle through layouts of the target workspace",
+		"increase_master":           "Increase number of windows in master column/row",
+		"decrease_master":           "Decrease number of windows in master column/row",
+		"increment_master":          "Grow width/height of master column/row",
+		"decrement_master":          "Shrink width/height of master column/row",
+	}
+
 	// TODO: Remove when keybind dispatching will be redone
 	for k, v := range keybindActions {
 		actions[k] = CommandWrap{
-			fn: wrapActionToCommandFunc(v),
+			description: keybindDescriptions[k],
+			fn:          wrapActionToCommandFunc(v),
 		}
 	}
 
 	setters := CommandMap{
 		"layout": CommandWrap{
 			minIn: 1, maxIn: 1,
+			args: []types.ArgSpec{
+				{Name: "LAYOUT_NAME", Type: types.ArgEnum, Values: layoutNames},
+			},
+			description: "Set the layout of the target workspace, none untiles it",
 			fn: func(args ...string) ([]string, error) {
 				layoutName := args[0]
 				ws := tracker.Workspace(ctx.TargetWorkspaceNum)
@@ -205,34 +355,197 @@ func InitCommands(tracker Tracker, config *config.Config) Commands {
 				}
 			},
 		},
+		"layouts": CommandWrap{
+			minIn: 1, maxIn: maxLayouts,
+			args:        layoutsArgs(),
+			description: "Set the layouts of the target workspace to cycle through",
+			fn: func(args ...string) ([]string, error) {
+				// Both "set layouts a b" and "set layouts 'a,b'" are accepted
+				var names []string
+				for _, arg := range args {
+					names = append(names, strings.Split(arg, ",")...)
+				}
+
+				ws := tracker.Workspace(ctx.TargetWorkspaceNum)
+				return nil, ws.SetLayouts(names)
+			},
+		},
+		"gap": CommandWrap{
+			minIn: 1, maxIn: 1,
+			args: []types.ArgSpec{
+				{Name: "GAP", Type: types.ArgInt},
+			},
+			description: "Set gap between windows of the target workspace",
+			fn: func(args ...string) ([]string, error) {
+				gap, err := commandparser.ParseInt("GAP", args[0])
+				if err != nil {
+					return nil, err
+				}
+
+				ws := tracker.Workspace(ctx.TargetWorkspaceNum)
+				return nil, ws.SetGap(gap)
+			},
+		},
+		"proportion": CommandWrap{
+			minIn: 1, maxIn: 1,
+			args: []types.ArgSpec{
+				{Name: "PROPORTION", Type: types.ArgFloat},
+			},
+			description: "Set proportion of the master area of the target workspace",
+			fn: func(args ...string) ([]string, error) {
+				proportion, err := commandparser.ParseFloat("PROPORTION", args[0])
+				if err != nil {
+					return nil, err
+				}
+
+				ws, err := tilingWorkspace(ctx.TargetWorkspaceNum)
+				if err != nil {
+					return nil, err
+				}
+				return nil, ws.SetProportion(proportion)
+			},
+		},
+		"master_count": CommandWrap{
+			minIn: 1, maxIn: 1,
+			args: []types.ArgSpec{
+				{Name: "COUNT", Type: types.ArgInt},
+			},
+			description: "Set number of master windows of the target workspace",
+			fn: func(args ...string) ([]string, error) {
+				count, err := commandparser.ParseInt("COUNT", args[0])
+				if err != nil {
+					return nil, err
+				}
+
+				ws, err := tilingWorkspace(ctx.TargetWorkspaceNum)
+				if err != nil {
+					return nil, err
+				}
+				return nil, ws.SetMasterCount(count)
+			},
+		},
+		"remove_decorations": CommandWrap{
+			minIn: 1, maxIn: 1,
+			args: []types.ArgSpec{
+				{Name: "SWITCH", Type: types.ArgBool, Values: commandparser.SwitchValues},
+			},
+			description: "Set whether decorations of the windows in the target workspace are removed while tiling",
+			fn: func(args ...string) ([]string, error) {
+				hide, err := commandparser.ParseSwitch("SWITCH", args[0])
+				if err != nil {
+					return nil, err
+				}
+
+				ws := tracker.Workspace(ctx.TargetWorkspaceNum)
+				ws.SetHideDecorations(hide)
+				return nil, nil
+			},
+		},
+		"start_tiling": CommandWrap{
+			minIn: 1, maxIn: 1,
+			args: []types.ArgSpec{
+				{Name: "SWITCH", Type: types.ArgBool, Values: commandparser.SwitchValues},
+			},
+			description: "Set whether the target workspace is tiling when it is created",
+			fn: func(args ...string) ([]string, error) {
+				startTiling, err := commandparser.ParseSwitch("SWITCH", args[0])
+				if err != nil {
+					return nil, err
+				}
+
+				ws := tracker.Workspace(ctx.TargetWorkspaceNum)
+				ws.SetStartTiling(startTiling)
+				return nil, nil
+			},
+		},
 	}
 	queries := CommandMap{
 		"layout": CommandWrap{
 			minIn: 0, maxIn: 0,
+			description: "Print layout of the target workspace",
 			fn: func(args ...string) ([]string, error) {
 				ws := tracker.Workspace(ctx.TargetWorkspaceNum)
-				if ws.isTiling {
-					return []string{ws.ActiveLayoutName()}, nil
-				} else {
-					return []string{"none"}, nil
+				return []string{tilingLayoutName(ws)}, nil
+			},
+		},
+		"windows": CommandWrap{
+			minIn: 0, maxIn: 0,
+			description: "Print all tracked windows",
+			recordFn: func(args ...string) ([]types.Record, error) {
+				clients := tracker.Clients()
+				records := make([]types.Record, len(clients))
+				for i, client := range clients {
+					records[i] = formatWindowRecord(client, tracker)
+				}
+				return records, nil
+			},
+		},
+		"workspaces": CommandWrap{
+			minIn: 0, maxIn: 0,
+			description: "Print all workspaces",
+			recordFn: func(args ...string) ([]types.Record, error) {
+				records := make([]types.Record, tracker.WorkspaceCount())
+				for num := range tracker.WorkspaceCount() {
+					records[num] = formatWorkspaceRecord(num, tracker)
 				}
+				return records, nil
+			},
+		},
+		"active_window": CommandWrap{
+			minIn: 0, maxIn: 0,
+			description: "Print ID of the active window",
+			fn: func(args ...string) ([]string, error) {
+				client, exists := tracker.ActiveClient()
+				if !exists {
+					return nil, NoActiveWindow
+				}
+				return []string{client.Id().String()}, nil
+			},
+		},
+		"master_count": CommandWrap{
+			minIn: 0, maxIn: 0,
+			description: "Print number of master windows of the target workspace",
+			fn: func(args ...string) ([]string, error) {
+				ws := tracker.Workspace(ctx.TargetWorkspaceNum)
+				masterCount := ws.ActiveLayout().sto().MasterCount()
+				return []string{strconv.Itoa(masterCount)}, nil
+			},
+		},
+		"proportion": CommandWrap{
+			minIn: 0, maxIn: 0,
+			description: "Print proportion of the master area of the target workspace",
+			fn: func(args ...string) ([]string, error) {
+				ws := tracker.Workspace(ctx.TargetWorkspaceNum)
+				proportion := ws.ActiveLayout().GetProportion()
+				return []string{formatProportion(proportion)}, nil
+			},
+		},
+		"marks": CommandWrap{
+			minIn: 0, maxIn: 0,
+			description: "Print all marks",
+			recordFn: func(args ...string) ([]types.Record, error) {
+				return ctx.Marks.Records(), nil
 			},
 		},
 		"next_window": CommandWrap{
 			minIn: 0, maxIn: 1,
+			args: []types.ArgSpec{
+				{Name: "OFFSET", Type: types.ArgInt},
+			},
+			description: "Print the window next to the target window, or the one OFFSET windows away",
 			fn: func(args ...string) ([]string, error) {
 
-				var offset int64 = 1
+				offset := 1
 				if len(args) == 1 {
 					var err error
-					offset, err = strconv.ParseInt(args[0], 10, 64)
+					offset, err = commandparser.ParseInt("OFFSET", args[0])
 					if err != nil {
-						return nil, fmt.Errorf("Parse error for offset \"%v\": %w", args[0], err)
+						return nil, err
 					}
 				}
 
 				ws := tracker.Workspace(ctx.TargetWorkspaceNum)
-				client, found := ws.ActiveLayout().ClientRelative(ctx.TargetClient, int(offset))
+				client, found := ws.ActiveLayout().ClientRelative(ctx.TargetClient, offset)
 				if !found {
 					return nil, NoWindowInWorkspace
 				}
@@ -245,82 +558,180 @@ func InitCommands(tracker Tracker, config *config.Config) Commands {
 	fors := CommandMap{
 		"window": CommandWrap{
 			minIn: 1, maxIn: 1,
+			args: []types.ArgSpec{
+				{Name: "WID", Type: types.ArgWindow},
+			},
+			description: "Set target window",
 			fn: func(args ...string) ([]string, error) {
 				cid, err := parseClient(args[0], ctx, tracker)
 				if err != nil {
-					err = fmt.Errorf("Parse error for client id \"%v\": %w", args[0], err)
+					return nil, err
 				}
 
 				ctx.TargetClient = cid
 
-				return nil, err
+				return nil, nil
 			},
 		},
 		"workspace": CommandWrap{
 			minIn: 1, maxIn: 1,
+			args: []types.ArgSpec{
+				{Name: "WORKSPACE", Type: types.ArgWorkspace},
+			},
+			description: "Set target workspace",
 			fn: func(args ...string) ([]string, error) {
-				workspaceNum, err := strconv.ParseUint(args[0], 10, 64)
+				workspaceNum, err := parseWorkspace(args[0], ctx, tracker)
 				if err != nil {
-					err = fmt.Errorf("Parse error for workspace number \"%v\": %w", args[0], err)
-				} else if workspaceNum >= uint64(tracker.WorkspaceCount()) {
-					err = fmt.Errorf("Parse error for workspace number \"%v\": number is out of range", args[0])
+					return nil, err
 				}
 
-				ctx.TargetWorkspaceNum = uint(workspaceNum)
+				ctx.TargetWorkspaceNum = workspaceNum
 
-				return nil, err
+				return nil, nil
 			},
 		},
 	}
 
 	commandCollection := Commands{
-		Actions: actions,
-		Queries: queries,
-		Setters: setters,
-		Fors:    fors,
+		Actions:  actions,
+		Queries:  queries,
+		Setters:  setters,
+		Fors:     fors,
+		Foreachs: CommandMap{},
+
+		marks: ctx.Marks,
+		tx:    tx,
+	}
+
+	queries["commands"] = CommandWrap{
+		minIn: 0, maxIn: 0,
+		description: "Print all commands",
+		recordFn: func(args ...string) ([]types.Record, error) {
+			return commandCollection.Records(), nil
+		},
+	}
+
+	commandCollection.registerForeachs(ctx, tracker)
+	if config != nil {
+		commandCollection.registerAliases(config.Aliases)
 	}
 
 	return commandCollection
 }
 
+// Every layout name is an optional argument of set layouts except the first one
+func layoutsArgs() []types.ArgSpec {
+	args := make([]types.ArgSpec, len(config.LayoutNames))
+	for i := range args {
+		args[i] = types.ArgSpec{Name: fmt.Sprintf("LAYOUT_%d", i+1), Type: types.ArgEnum, Values: config.LayoutNames}
+	}
+	return args
+}
+
 func parseClient(arg string, ctx *CommandContext, tr Tracker) (Client, error) {
+	if client, isSelector, err := selectClient(arg, ctx, tr); isSelector {
+		return client, err
+	}
+
 	var client Client
 	var err error = nil
 
 	switch {
 	case arg == "%target":
 		client = ctx.TargetClient
+		if client == nil {
+			err = NoTargetWindow
+		}
 	case arg == "%queried":
 		fallthrough
 	case strings.HasPrefix(arg, "%"):
 		var exists bool
 		client, exists = ctx.Variables[arg]
 		if !exists {
-			err = fmt.Errorf("Parse error for client id \"%v\": variable do not exists", arg)
+			client, exists = ctx.Marks.Get(arg[1:])
+		}
+		if !exists {
+			err = fmt.Errorf("%w: \"%v\"", MarkNotExists, arg[1:])
 		}
 	default:
-		var exists bool
-		id, err := tr.ParseClientId(arg)
-		if err != nil {
-			return nil, fmt.Errorf("Parse error for client id \"%v\": %w", arg, err)
+		id, parseErr := tr.ParseClientId(arg)
+		if parseErr != nil {
+			return nil, fmt.Errorf("%w WID \"%v\": expected window id or selector", commandparser.InvalidArgument, arg)
 		}
+
+		var exists bool
 		client, exists = tr.Client(id)
 		if !exists {
-			err = fmt.Errorf("Client id \"%v\" is not tracked", arg)
+			err = fmt.Errorf("%w: %v", WindowNotTracked, arg)
 		}
 	}
 
 	return client, err
 }
 
+// Name of the active layout or "none" if the workspace is not tiling
+func tilingLayoutName(ws *Workspace) string {
+	if ws.IsTiling() {
+		return ws.ActiveLayoutName()
+	} else {
+		return "none"
+	}
+}
+
+func formatProportion(proportion float64) string {
+	return strconv.FormatFloat(proportion, 'f', 2, 64)
+}
+
+// Format window as record of id, class, title, workspace, role and geometry
+func formatWindowRecord(client Client, tr Tracker) types.Record {
+	role := "floating"
+	if ws := tr.Workspace(client.WorkspaceNum()); ws != nil {
+		role = ws.ClientRole(client)
+	}
+
+	geometry := ""
+	if x, y, width, height, err := client.Geometry(); err == nil {
+		geometry = fmt.Sprintf("%dx%d+%d+%d", width, height, x, y)
+	}
+
+	return types.Record{
+		{Name: "id", Value: client.Id().String()},
+		{Name: "class", Value: client.Class()},
+		{Name: "title", Value: client.Title()},
+		{Name: "workspace", Value: strconv.FormatUint(uint64(client.WorkspaceNum()), 10)},
+		{Name: "role", Value: role},
+		{Name: "geometry", Value: geometry},
+	}
+}
+
+// Format workspace as record of number, name, tiling flag, layout, master count, proportion and gap
+func formatWorkspaceRecord(num uint, tr Tracker) types.Record {
+	ws := tr.Workspace(num)
+	layout := ws.ActiveLayout()
+
+	return types.Record{
+		{Name: "workspace", Value: strconv.FormatUint(uint64(num), 10)},
+		{Name: "name", Value: tr.WorkspaceName(num)},
+		{Name: "tiling", Value: strconv.FormatBool(ws.IsTiling())},
+		{Name: "layout", Value: ws.ActiveLayoutName()},
+		{Name: "master_count", Value: strconv.Itoa(layout.sto().MasterCount())},
+		{Name: "proportion", Value: formatProportion(layout.GetProportion())},
+		{Name: "gap", Value: strconv.Itoa(ws.Gap())},
+	}
+}
+
 // TODO: Remove when keybind dispatching will be redone
-func wrapActionToCommandFunc(fn func()) commandFunc {
+func wrapActionToCommandFunc(fn func() error) commandFunc {
 	return func(s ...string) ([]string, error) {
-		fn()
-		return nil, nil
+		return nil, fn()
 	}
 }
 
+// ForgetClient removes everything referencing the client, e.g. its marks
+func (c Commands) ForgetClient(id string) {
+	c.marks.Forget(id)
+}
+
 func (c Commands) Map(kind types.CommandType) CommandMap {
 	switch kind {
 	case types.Action:
@@ -331,11 +742,48 @@ func (c Commands) Map(kind types.CommandType) CommandMap {
 		return c.Queries
 	case types.For:
 		return c.Fors
+	case types.Foreach:
+		return c.Foreachs
 	default:
 		return nil
 	}
 }
 
+// Records of all the commands except internal ones with their kind, name,
+// number of arguments, usage and description
+func (c Commands) Records() []types.Record {
+	kinds := []types.CommandType{types.Action, types.Set, types.Query, types.For, types.Foreach}
+
+	records := make([]types.Record, 0)
+	for _, kind := range kinds {
+		commandMap := c.Map(kind)
+		for _, name := range slices.Sorted(maps.Keys(commandMap)) {
+			if strings.HasPrefix(name, "__") {
+				continue
+			}
+			command := commandMap[name]
+			records = append(records, types.Record{
+				{Name: "kind", Value: strings.ToLower(string(kind))},
+				{Name: "name", Value: name},
+				{Name: "min_args", Value: strconv.Itoa(command.MinIn())},
+				{Name: "max_args", Value: strconv.Itoa(command.MaxIn())},
+				{Name: "usage", Value: command.Usage()},
+				{Name: "description", Value: command.Description()},
+			})
+		}
+	}
+	return records
+}
+
+// Parser of command strings into the commands of the collection
+func (c Commands) parser() commandparser.CommandParser {
+	return commandparser.CommandParser{
+		GetCommandByName: func(kind types.CommandType, name string) (commandparser.CommandWrap, bool) {
+			return c.GetByName(kind, name)
+		},
+	}
+}
+
 func (c Commands) GetByName(kind types.CommandType, name string) (CommandWrap, bool) {
 	mapOfKind := c.Map(kind)
 	if mapOfKind == nil {
@@ -348,14 +796,24 @@ func (c Commands) GetByName(kind types.CommandType, name string) (CommandWrap, b
 func (c Commands) Do(command types.Command) types.CommandResult {
 	commandMap := c.Map(command.Kind)
 	if commandMap == nil {
+		c.tx.Rollback()
 		return types.CommandResult{Messages: nil, Err: UnknownCommandType}
 	}
 
 	commandWrap, exists := commandMap[command.Name]
 	if !exists {
+		c.tx.Rollback()
 		return types.CommandResult{Messages: nil, Err: CommandNotExists}
-	} else {
-		messages, err := commandWrap.Call(command.Args...)
-		return types.CommandResult{Messages: messages, Err: err}
 	}
+
+	isInternal := strings.HasPrefix(command.Name, "__")
+	if c.tx.IsAborted() && !isInternal {
+		return types.CommandResult{Messages: nil, Err: SequenceAborted}
+	}
+
+	result := commandWrap.Call(command.Args...)
+	if result.Err != nil {
+		c.tx.Rollback()
+	}
+	return result
 }
@@ -3,12 +3,12 @@ package daemon
 import (
 	"os"
 	"os/signal"
-	"sync"
 	"syscall"
 
 	commandparser "github.com/Alnivel/zentile/internal/command_parser"
 	"github.com/Alnivel/zentile/internal/config"
 	"github.com/Alnivel/zentile/internal/daemon/backend"
+	"github.com/Alnivel/zentile/internal/protocol"
 	"github.com/Alnivel/zentile/internal/types"
 	log "github.com/sirupsen/logrus"
 )
@@ -17,38 +17,55 @@ type Client = backend.Client
 type Tracker = backend.Tracker[*Workspace]
 type Keybinder = backend.Keybinder
 
-func Start(config config.Config, args []string) {
+// Start the daemon listening for the commands on the socket path
+func Start(config config.Config, socketPath string) {
 	pingQuit := make(chan struct{}, 1)
 	go handleInterruptsGracefully(pingQuit)
 
+	// Listen before touching the windows, so another running instance is detected early.
+	// The socket file is removed when the listener is closed.
+	socketListener, err := ListenSocket(socketPath, config.MaxMessageLength)
+	if err != nil {
+		log.Error(err.Error())
+		return
+	}
+	defer socketListener.Close()
+
 	x11Backend, err := backend.NewX11Backend()
 	if err != nil {
 		log.Error(err.Error())
 		return
 	}
 
-	workspaceFactory := WorkspaceFactory{&config}
-	windowTracker, err := backend.NewTrackerFor(x11Backend, config.WindowsToIgnore, workspaceFactory.NewWorkspace)
+	events := NewEventBus()
+	animationFrames := make(chan func())
+	transaction := &Transaction{}
+	workspaceFactory := NewWorkspaceFactory(&config, animationFrames, events, transaction)
+	trackerOptions := backend.TrackerOptions{
+		ClassesToIgnore: config.WindowsToIgnore,
+		StickyPolicy:    backend.StickyPolicy(config.StickyWindows),
+		TransientPolicy: backend.TransientPolicy(config.TransientWindows),
+		OnEvent: func(event backend.TrackerEvent, args ...string) {
+			events.Publish(protocol.EventName(event), args...)
+		},
+	}
+	windowTracker, err := backend.NewTrackerFor(x11Backend, trackerOptions, workspaceFactory.NewWorkspace)
 	if err != nil {
 		log.Error(err.Error())
 		return
 	}
 
 	windowTracker.StartTracking()
-	commands := InitCommands(windowTracker, &config)
+	commands := InitCommands(windowTracker, transaction, &config)
+	events.Handle(protocol.WindowRemoved, func(event Event) {
+		commands.ForgetClient(event.Args[0])
+	})
 
 	pingBeforeXEvent, pingAfterXEvent, pingXQuit := backend.NewMainLoopFor(x11Backend)
 	commandChan := make(chan CommandRequest)
-	commandChanMutex := sync.Mutex{}
+	sequenceLock := &SequenceLock{}
 
-	socketPath := "/tmp/zentile.sock"
-	socketListener, err := ListenSocket(socketPath)
-	if err != nil {
-		log.Error(err.Error())
-		return
-	}
-	defer socketListener.Close()
-	socketListener.HandleIncomingCommands(commandChan, &commandChanMutex)
+	socketListener.HandleIncomingCommands(commandChan, sequenceLock, events)
 
 	getCommandByNameAdapter := func(kind types.CommandType, name string) (commandparser.CommandWrap, bool) {
 		return commands.GetByName(kind, name)
@@ -67,7 +84,7 @@ func Start(config config.Config, args []string) {
 		keybinder: backend.NewKeybinderFor(x11Backend),
 		commands:  commandKeybinings,
 	}
-	keybindings.HandleIncomingCommands(commandChan, &commandChanMutex)
+	keybindings.HandleIncomingCommands(commandChan, sequenceLock)
 
 	for {
 		select {
@@ -81,6 +98,9 @@ func Start(config config.Config, args []string) {
 			log.Debugf("The command is done with result %v", result)
 			commandRequest.SendResult(result)
 
+		case applyFrame := <-animationFrames:
+			applyFrame()
+
 		case <-pingXQuit:
 			return
 		case <-pingQuit:
@@ -0,0 +1,76 @@
+package daemon
+
+import (
+	"errors"
+	"fmt"
+
+	commandparser "github.com/Alnivel/zentile/internal/command_parser"
+	"github.com/Alnivel/zentile/internal/config"
+	"github.com/Alnivel/zentile/internal/protocol"
+)
+
+// Categories of the errors, re-exported from protocol so the commands can return them.
+// More specific errors of the commands are reported with the code of their category, see CodeOf.
+var (
+	UnknownCommand      = protocol.UnknownCommand
+	BadArguments        = protocol.BadArguments
+	WindowNotFound      = protocol.WindowNotFound
+	WorkspaceOutOfRange = protocol.WorkspaceOutOfRange
+	NotTiling           = protocol.NotTiling
+	BackendFailure      = protocol.BackendFailure
+	SequenceAborted     = protocol.SequenceAborted
+)
+
+// Errors of each code, the first error is the category of the code
+var errorCodes = []struct {
+	code protocol.ErrorCode
+	errs []error
+}{
+	{protocol.CodeUnknownCommand, []error{
+		UnknownCommand, CommandNotExists, UnknownCommandType, commandparser.UnknownCommand,
+	}},
+	{protocol.CodeBadArguments, []error{
+		BadArguments, IncorrectNumberOfArgs, MultipleWorkspaces, AmbiguousWindow,
+		InvalidFilter, InvalidSelector, InvalidMarkName, InvalidMasterCount, AliasTooDeep,
+		commandparser.InvalidArgument, commandparser.TooFewArguments,
+		commandparser.MissingBlock, commandparser.UnterminatedBlock, commandparser.UnexpectedBlockEnd,
+		commandparser.UnterminatedQuote, commandparser.DanglingEscape,
+		config.InvalidGap, config.InvalidProportion, config.InvalidLayoutName, config.DuplicateLayout, config.NoLayouts,
+	}},
+	{protocol.CodeWindowNotFound, []error{
+		WindowNotFound, NoWindowMatches, WindowNotTracked, MarkNotExists,
+		NoActiveWindow, NoTargetWindow, NoWindowInWorkspace,
+	}},
+	{protocol.CodeWorkspaceOutOfRange, []error{
+		WorkspaceOutOfRange, UnknownWorkspace,
+	}},
+	{protocol.CodeNotTiling, []error{
+		NotTiling,
+	}},
+	{protocol.CodeBackendFailure, []error{
+		BackendFailure,
+	}},
+	{protocol.CodeAborted, []error{
+		SequenceAborted,
+	}},
+}
+
+// Code of the error category
+func CodeOf(err error) protocol.ErrorCode {
+	for _, entry := range errorCodes {
+		for _, codeErr := range entry.errs {
+			if errors.Is(err, codeErr) {
+				return entry.code
+			}
+		}
+	}
+	return protocol.CodeFailed
+}
+
+// Error of the window system wrapped as BackendFailure, nil if there is none
+func backendError(err error) error {
+	if err == nil {
+		return nil
+	}
+	return fmt.Errorf("%w: %w", BackendFailure, err)
+}
@@ -0,0 +1,110 @@
+package daemon
+
+import (
+	"slices"
+	"sync"
+
+	"github.com/Alnivel/zentile/internal/protocol"
+	log "github.com/sirupsen/logrus"
+)
+
+// How many events could wait for a slow subscriber before being dropped
+const SUBSCRIPTION_BUFFER_SIZE = 64
+
+type Event struct {
+	Name protocol.EventName
+	Args []string
+}
+
+// EventBus delivers published events to the subscribers and handlers
+type EventBus struct {
+	mutex       sync.Mutex
+	subscribers map[*Subscription]struct{}
+	handlers    map[protocol.EventName][]func(Event)
+}
+
+type Subscription struct {
+	Events <-chan Event
+	events chan Event
+	names  []protocol.EventName // Subscribed events, all events if empty
+	bus    *EventBus
+}
+
+func NewEventBus() *EventBus {
+	return &EventBus{
+		subscribers: make(map[*Subscription]struct{}),
+		handlers:    make(map[protocol.EventName][]func(Event)),
+	}
+}
+
+// Handle registers the handler to be called synchronously on each published event with the name
+func (bus *EventBus) Handle(name protocol.EventName, handler func(Event)) {
+	bus.mutex.Lock()
+	defer bus.mutex.Unlock()
+
+	bus.handlers[name] = append(bus.handlers[name], handler)
+}
+
+// Subscribe to the events with provided names or to all events if no names provided
+func (bus *EventBus) Subscribe(names []protocol.EventName) *Subscription {
+	events := make(chan Event, SUBSCRIPTION_BUFFER_SIZE)
+	sub := &Subscription{
+		Events: events,
+		events: events,
+		names:  names,
+		bus:    bus,
+	}
+
+	bus.mutex.Lock()
+	defer bus.mutex.Unlock()
+	bus.subscribers[sub] = struct{}{}
+
+	return sub
+}
+
+// Publish sends the event to the interested subscribers without blocking,
+// the event is dropped for subscribers that are too slow to receive it.
+// It is safe to publish to nil bus.
+func (bus *EventBus) Publish(name protocol.EventName, args ...string) {
+	if bus == nil {
+		return
+	}
+
+	event := Event{Name: name, Args: args}
+
+	bus.mutex.Lock()
+	handlers := slices.Clone(bus.handlers[name])
+	for sub := range bus.subscribers {
+		if len(sub.names) > 0 && !slices.Contains(sub.names, name) {
+			continue
+		}
+
+		select {
+		case sub.events <- event:
+		default:
+			log.Warnf("Subscriber is too slow, dropping event %v", event)
+		}
+	}
+	bus.mutex.Unlock()
+
+	// Handlers are called without the lock held, so they can use the bus too
+	for _, handler := range handlers {
+		handler(event)
+	}
+}
+
+// Close stops delivering events to the subscription and closes its channel
+func (sub *Subscription) Close() {
+	sub.bus.mutex.Lock()
+	defer sub.bus.mutex.Unlock()
+
+	if _, subscribed := sub.bus.subscribers[sub]; subscribed {
+		delete(sub.bus.subscribers, sub)
+		close(sub.events)
+	}
+}
+
+func parseEventName(name string) (protocol.EventName, bool) {
+	eventName := protocol.EventName(name)
+	return eventName, slices.Contains(protocol.EventNames, eventName)
+}
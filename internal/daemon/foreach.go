@@ -0,0 +1,125 @@
+package daemon
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/Alnivel/zentile/internal/types"
+)
+
+var InvalidFilter = errors.New("Invalid window filter")
+
+// Register commands running a block of commands for each window or workspace.
+// The target is set to each of them in turn and restored afterwards.
+func (c Commands) registerForeachs(ctx *CommandContext, tracker Tracker) {
+	parser := c.parser()
+
+	runBlock := func(block string, forEach func(runOnce func() error) error) ([]string, error) {
+		commands, err := parser.ParseString(block)
+		if err != nil {
+			return nil, fmt.Errorf("Error in block: %w", err)
+		}
+
+		var messages []string
+		err = forEach(func() error {
+			for _, command := range commands {
+				result := c.Do(command)
+				messages = append(messages, result.Messages...)
+				if result.Err != nil {
+					return result.Err
+				}
+			}
+			return nil
+		})
+		return messages, err
+	}
+
+	c.Foreachs["window"] = CommandWrap{
+		minIn: 1, maxIn: 2,
+		args: []types.ArgSpec{
+			{Name: "FILTER", Type: types.ArgString},
+			{Name: "COMMANDS", Type: types.ArgString},
+		},
+		usage:       "[FILTER] { COMMANDS }",
+		block:       true,
+		description: "Run the commands for each window matching the filter",
+		fn: func(args ...string) ([]string, error) {
+			filter := ""
+			if len(args) == 2 {
+				filter = args[0]
+			}
+			matches, err := parseWindowFilter(filter, ctx, tracker)
+			if err != nil {
+				return nil, err
+			}
+
+			savedClient := ctx.TargetClient
+			defer func() { ctx.TargetClient = savedClient }()
+
+			return runBlock(args[len(args)-1], func(runOnce func() error) error {
+				for _, client := range tracker.Clients() {
+					if !matches(client) {
+						continue
+					}
+
+					ctx.TargetClient = client
+					if err := runOnce(); err != nil {
+						return err
+					}
+				}
+				return nil
+			})
+		},
+	}
+
+	c.Foreachs["workspace"] = CommandWrap{
+		minIn: 1, maxIn: 2,
+		args: []types.ArgSpec{
+			{Name: "WORKSPACE", Type: types.ArgWorkspace},
+			{Name: "COMMANDS", Type: types.ArgString},
+		},
+		usage:       "[WORKSPACE] { COMMANDS }",
+		block:       true,
+		description: "Run the commands for each workspace, or for the workspace",
+		fn: func(args ...string) ([]string, error) {
+			workspace := "all"
+			if len(args) == 2 {
+				workspace = args[0]
+			}
+			workspaceNums, err := parseWorkspaces(workspace, ctx, tracker)
+			if err != nil {
+				return nil, err
+			}
+
+			savedWorkspaceNum := ctx.TargetWorkspaceNum
+			defer func() { ctx.TargetWorkspaceNum = savedWorkspaceNum }()
+
+			return runBlock(args[len(args)-1], func(runOnce func() error) error {
+				for _, num := range workspaceNums {
+					ctx.TargetWorkspaceNum = num
+					if err := runOnce(); err != nil {
+						return err
+					}
+				}
+				return nil
+			})
+		},
+	}
+}
+
+// Parse filter of windows: class:CLASS, title~=REGEX or workspace:WORKSPACE, empty filter matches every window
+func parseWindowFilter(filter string, ctx *CommandContext, tr Tracker) (func(Client) bool, error) {
+	if filter == "" {
+		return func(Client) bool { return true }, nil
+	}
+
+	matches, isMatcher, err := parseWindowMatcher(filter, ctx, tr)
+	switch {
+	case !isMatcher:
+		return nil, fmt.Errorf("%w \"%v\": expected class:CLASS, title~=REGEX or workspace:WORKSPACE", InvalidFilter, filter)
+	case err != nil:
+		return nil, fmt.Errorf("%w \"%v\": %w", InvalidFilter, filter, err)
+	default:
+		return matches, nil
+	}
+}
@@ -10,20 +10,23 @@ type FullScreen struct {
 	WorkspaceNum uint
 	Tracker Tracker
 	Config *config.WorkspaceConfig
+	Animator *Animator
 }
 
-func (fs *FullScreen) Do() {
+func (fs *FullScreen) Do() error {
 	log.Info("Switching to Fullscreen layout")
-	for _, c := range fs.Store.All() {
+	clients := fs.Store.All()
+	placements := make([]Placement, 0, len(clients))
+	for _, c := range clients {
 		x, y, w, h := fs.Tracker.WorkAreaDimensions(fs.WorkspaceNum)
-		c.MoveResize(x, y, w, h)
+		placements = append(placements, Placement{c, Geometry{x, y, w, h}})
 	}
+	return fs.Animator.Apply(placements)
 }
 
-func (fs *FullScreen) Undo() {
-	for _, c := range append(fs.masters, fs.slaves...) {
-		c.Restore()
-	}
+func (fs *FullScreen) Undo() error {
+	fs.Animator.Cancel()
+	return restoreClients(fs.All())
 }
 
 func (fs *FullScreen) GetProportion() float64 {
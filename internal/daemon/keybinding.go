@@ -17,24 +17,50 @@ type Keybindings struct {
 	commands map[string][]types.Command
 }
 
-func (k Keybindings) HandleIncomingCommands(commandChan chan<- CommandRequest, chanMutex *sync.Mutex) {
-	for keyStr, command := range k.commands {
+// Number of key presses waiting for their command sequences to run
+const keybindingQueueLength = 16
+
+// Keybinding callbacks are called while the main loop waits for the X event to be processed,
+// so the command sequences are queued and run one by one outside of the callbacks.
+func (k Keybindings) HandleIncomingCommands(commandChan chan<- CommandRequest, lock *SequenceLock) {
+	queue := make(chan []types.Command, keybindingQueueLength)
+	go func() {
+		for commands := range queue {
+			runKeybinding(commands, commandChan, lock)
+		}
+	}()
+
+	for keyStr, commands := range k.commands {
 		k.keybinder.Bind(keyStr, func() {
-			chanMutex.Lock()
-			defer chanMutex.Unlock()
-			requestStartNewCommandSequence(commandChan)
-
-			for _, command := range command {
-				commandRequest, replyChan := NewCommandRequest(command)
-
-				commandChan <- commandRequest
-				result := <-replyChan
-				if result.Err != nil {
-					log.Error(result.Err.Error())
-					break
-				}
+			select {
+			case queue <- commands:
+			default:
+				log.Warnf("Too many keys pressed, %v is ignored", keyStr)
 			}
 		})
 	}
 }
 
+// Run the command sequence ahead of the socket clients waiting for their turn
+func runKeybinding(commands []types.Command, commandChan chan<- CommandRequest, lock *SequenceLock) {
+	lock.LockPriority()
+	defer lock.Unlock()
+	requestStartNewCommandSequence(commandChan)
+	defer func() {
+		if err := requestEndCommandSequence(commandChan); err != nil {
+			log.Error(err.Error())
+		}
+	}()
+
+	for _, command := range commands {
+		commandRequest, replyChan := NewCommandRequest(command)
+
+		commandChan <- commandRequest
+		result := <-replyChan
+		if result.Err != nil {
+			log.Error(result.Err.Error())
+			break
+		}
+	}
+}
+
@@ -1,6 +1,7 @@
 package daemon
 
 import (
+	"errors"
 	"math"
 
 	"github.com/Alnivel/zentile/internal/config"
@@ -12,8 +13,8 @@ const (
 )
 
 type Layout interface {
-	Do()
-	Undo()
+	Do() error
+	Undo() error
 	Add(client Client)
 	Remove(client Client)
 
@@ -35,12 +36,21 @@ type VertHorz struct {
 	WorkspaceNum uint
 	Tracker Tracker
 	Config *config.WorkspaceConfig
+	Animator *Animator
 }
 
-func (l *VertHorz) Undo() {
-	for _, c := range append(l.masters, l.slaves...) {
-		c.Restore()
+func (l *VertHorz) Undo() error {
+	l.Animator.Cancel()
+	return restoreClients(l.All())
+}
+
+// Restore the clients to their pre-tiling state
+func restoreClients(clients []Client) error {
+	var errs []error
+	for _, c := range clients {
+		errs = append(errs, c.Restore())
 	}
+	return backendError(errors.Join(errs...))
 }
 
 func (l *VertHorz) GetProportion() float64 {
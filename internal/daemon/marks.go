@@ -0,0 +1,89 @@
+package daemon
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/Alnivel/zentile/internal/types"
+)
+
+var (
+	MarkNotExists   = errors.New("Mark does not exist")
+	InvalidMarkName = errors.New("Invalid mark name")
+)
+
+// Names of the variables and selectors that cannot be used as marks
+var reservedMarkNames = append([]string{"target", "queried"}, selectorNames()...)
+
+func selectorNames() []string {
+	names := make([]string, len(WindowSelectors))
+	for i, selector := range WindowSelectors {
+		names[i] = strings.TrimPrefix(selector, "%")
+	}
+	return names
+}
+
+// Marks are named windows kept across command sequences
+// and referenced as %NAME wherever window id is accepted.
+type Marks struct {
+	clients map[string]Client
+}
+
+func NewMarks() *Marks {
+	return &Marks{
+		clients: make(map[string]Client),
+	}
+}
+
+// Parse mark name, the leading % is optional.
+// Names with colon are left for the selectors with parameters, such as %index:N
+func parseMarkName(name string) (string, error) {
+	name = strings.TrimPrefix(name, "%")
+
+	if name == "" || slices.Contains(reservedMarkNames, name) || strings.Contains(name, ":") {
+		return "", fmt.Errorf("%w: \"%v\"", InvalidMarkName, name)
+	}
+	return name, nil
+}
+
+func (m *Marks) Set(name string, client Client) {
+	m.clients[name] = client
+}
+
+func (m *Marks) Get(name string) (Client, bool) {
+	client, exists := m.clients[name]
+	return client, exists
+}
+
+func (m *Marks) Remove(name string) error {
+	if _, exists := m.clients[name]; !exists {
+		return fmt.Errorf("%w: \"%v\"", MarkNotExists, name)
+	}
+
+	delete(m.clients, name)
+	return nil
+}
+
+// Forget removes all marks of the client with provided id
+func (m *Marks) Forget(id string) {
+	maps.DeleteFunc(m.clients, func(name string, client Client) bool {
+		return client.Id().String() == id
+	})
+}
+
+// Records of the marks sorted by name
+func (m *Marks) Records() []types.Record {
+	names := slices.Sorted(maps.Keys(m.clients))
+
+	records := make([]types.Record, len(names))
+	for i, name := range names {
+		records[i] = types.Record{
+			{Name: "name", Value: name},
+			{Name: "id", Value: m.clients[name].Id().String()},
+		}
+	}
+	return records
+}
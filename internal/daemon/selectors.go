@@ -0,0 +1,150 @@
+package daemon
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	commandparser "github.com/Alnivel/zentile/internal/command_parser"
+)
+
+var (
+	NoWindowMatches = errors.New("No window matches")
+	AmbiguousWindow = errors.New("Several windows match")
+	InvalidSelector = errors.New("Invalid window selector")
+)
+
+// Selectors of a single window, accepted wherever window id is accepted.
+// %index:N is not listed, as it takes a parameter.
+var WindowSelectors = []string{"%master", "%first", "%last", "%last_focused", "%urgent"}
+
+// Parse matcher of windows: class:CLASS, title~=REGEX or workspace:WORKSPACE.
+// Returns false if the string is not a matcher.
+func parseWindowMatcher(s string, ctx *CommandContext, tr Tracker) (matches func(Client) bool, isMatcher bool, err error) {
+	switch {
+	case strings.HasPrefix(s, commandparser.ClassMatcherPrefix):
+		class := strings.TrimPrefix(s, commandparser.ClassMatcherPrefix)
+		return func(c Client) bool {
+			return strings.EqualFold(c.Class(), class)
+		}, true, nil
+	case strings.HasPrefix(s, commandparser.TitleMatcherPrefix):
+		re, err := regexp.Compile(strings.TrimPrefix(s, commandparser.TitleMatcherPrefix))
+		if err != nil {
+			return nil, true, err
+		}
+		return func(c Client) bool {
+			return re.MatchString(c.Title())
+		}, true, nil
+	case strings.HasPrefix(s, commandparser.WorkspaceMatcherPrefix):
+		workspaceNums, err := parseWorkspaces(strings.TrimPrefix(s, commandparser.WorkspaceMatcherPrefix), ctx, tr)
+		if err != nil {
+			return nil, true, err
+		}
+		return func(c Client) bool {
+			return slices.Contains(workspaceNums, c.WorkspaceNum())
+		}, true, nil
+	default:
+		return nil, false, nil
+	}
+}
+
+// Resolve selector of a single window.
+// Returns false if the argument is not a selector.
+func selectClient(arg string, ctx *CommandContext, tr Tracker) (client Client, isSelector bool, err error) {
+	if matches, isMatcher, err := parseWindowMatcher(arg, ctx, tr); isMatcher {
+		if err != nil {
+			return nil, true, fmt.Errorf("%w \"%v\": %w", InvalidSelector, arg, err)
+		}
+		client, err := uniqueClient(arg, tr.Clients(), matches)
+		return client, true, err
+	}
+
+	switch {
+	case arg == "%master":
+		st, err := targetStore(arg, ctx, tr)
+		if err != nil {
+			return nil, true, err
+		}
+		if len(st.masters) == 0 {
+			return nil, true, fmt.Errorf("%w \"%v\"", NoWindowMatches, arg)
+		}
+		// With several masters the first one is selected, like %first among all the windows
+		return st.masters[0], true, nil
+	case arg == "%first":
+		client, err := clientAtIndex(arg, 0, ctx, tr)
+		return client, true, err
+	case arg == "%last":
+		client, err := clientAtIndex(arg, -1, ctx, tr)
+		return client, true, err
+	case strings.HasPrefix(arg, commandparser.IndexSelectorPrefix):
+		index, err := strconv.Atoi(strings.TrimPrefix(arg, commandparser.IndexSelectorPrefix))
+		if err != nil {
+			return nil, true, fmt.Errorf("%w \"%v\": %w", InvalidSelector, arg, err)
+		}
+		client, err := clientAtIndex(arg, index, ctx, tr)
+		return client, true, err
+	case arg == "%last_focused":
+		client, exists := tr.LastActiveClient()
+		if !exists {
+			return nil, true, fmt.Errorf("%w \"%v\"", NoWindowMatches, arg)
+		}
+		return client, true, nil
+	case arg == "%urgent":
+		client, err := uniqueClient(arg, tr.Clients(), Client.Urgent)
+		return client, true, err
+	default:
+		return nil, false, nil
+	}
+}
+
+// Store of the active layout of the target workspace
+func targetStore(selector string, ctx *CommandContext, tr Tracker) (*Store, error) {
+	ws := tr.Workspace(ctx.TargetWorkspaceNum)
+	if ws == nil {
+		return nil, fmt.Errorf("%w \"%v\": no target workspace", NoWindowMatches, selector)
+	}
+	return ws.ActiveLayout().sto(), nil
+}
+
+// Window at the index in the layout of the target workspace, negative index counts from the end
+func clientAtIndex(selector string, index int, ctx *CommandContext, tr Tracker) (Client, error) {
+	st, err := targetStore(selector, ctx, tr)
+	if err != nil {
+		return nil, err
+	}
+	clients := st.All()
+
+	if index < 0 {
+		index += len(clients)
+	}
+	if index < 0 || index >= len(clients) {
+		return nil, fmt.Errorf("%w \"%v\": workspace %d has %d windows", NoWindowMatches, selector, ctx.TargetWorkspaceNum, len(clients))
+	}
+	return clients[index], nil
+}
+
+// The only client matching the selector, error lists the ids if there are several
+func uniqueClient(selector string, clients []Client, matches func(Client) bool) (Client, error) {
+	var found []Client
+	for _, client := range clients {
+		if matches(client) {
+			found = append(found, client)
+		}
+	}
+
+	switch len(found) {
+	case 0:
+		return nil, fmt.Errorf("%w \"%v\"", NoWindowMatches, selector)
+	case 1:
+		return found[0], nil
+	default:
+		ids := make([]string, len(found))
+		for i, client := range found {
+			ids[i] = client.Id().String()
+		}
+		return nil, fmt.Errorf("%w \"%v\": %v", AmbiguousWindow, selector, strings.Join(ids, ", "))
+	}
+}
@@ -0,0 +1,60 @@
+package daemon
+
+import "sync"
+
+// SequenceLock is held for the duration of a command sequence,
+// so commands of different sequences are not interleaved.
+// The waiters get the lock in the order they asked for it,
+// but the priority ones, such as keybindings, are served first.
+type SequenceLock struct {
+	mu       sync.Mutex
+	locked   bool
+	priority []chan struct{} // Waiters served before the normal ones
+	normal   []chan struct{}
+}
+
+func (l *SequenceLock) Lock() {
+	l.lock(false)
+}
+
+// LockPriority waits for the lock ahead of the waiters locked with Lock
+func (l *SequenceLock) LockPriority() {
+	l.lock(true)
+}
+
+func (l *SequenceLock) lock(priority bool) {
+	l.mu.Lock()
+	if !l.locked {
+		l.locked = true
+		l.mu.Unlock()
+		return
+	}
+
+	handover := make(chan struct{})
+	if priority {
+		l.priority = append(l.priority, handover)
+	} else {
+		l.normal = append(l.normal, handover)
+	}
+	l.mu.Unlock()
+
+	<-handover
+}
+
+// Unlock hands the lock over to the next waiter, if there is one
+func (l *SequenceLock) Unlock() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var handover chan struct{}
+	switch {
+	case len(l.priority) > 0:
+		handover, l.priority = l.priority[0], l.priority[1:]
+	case len(l.normal) > 0:
+		handover, l.normal = l.normal[0], l.normal[1:]
+	default:
+		l.locked = false
+		return
+	}
+	close(handover)
+}
@@ -0,0 +1,105 @@
+package daemon
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// Wait until the lock has the number of priority and normal waiters queued
+func waitForWaiters(t *testing.T, l *SequenceLock, priority, normal int) {
+	t.Helper()
+
+	for range 1000 {
+		l.mu.Lock()
+		queued := len(l.priority) == priority && len(l.normal) == normal
+		l.mu.Unlock()
+		if queued {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("Waiters were not queued, expecting %v priority and %v normal", priority, normal)
+}
+
+func TestSequenceLock_Handover(t *testing.T) {
+	tests := []struct {
+		name string
+
+		waiters []string // Queued in the order, the ones starting with "p" lock with priority
+		want    []string
+	}{
+		{
+			name:    "FIFO",
+			waiters: []string{"n1", "n2", "n3"},
+			want:    []string{"n1", "n2", "n3"},
+		},
+		{
+			name:    "PriorityBeforeNormal",
+			waiters: []string{"n1", "p1", "n2", "p2"},
+			want:    []string{"p1", "p2", "n1", "n2"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := &SequenceLock{}
+			l.Lock()
+
+			order := make(chan string, len(tt.waiters))
+			priority, normal := 0, 0
+			for _, name := range tt.waiters {
+				isPriority := name[0] == 'p'
+				go func() {
+					if isPriority {
+						l.LockPriority()
+					} else {
+						l.Lock()
+					}
+					order <- name
+					l.Unlock()
+				}()
+
+				// Queue the waiters one at a time, so their order is known
+				if isPriority {
+					priority++
+				} else {
+					normal++
+				}
+				waitForWaiters(t, l, priority, normal)
+			}
+
+			l.Unlock()
+
+			var got []string
+			for range tt.waiters {
+				select {
+				case name := <-order:
+					got = append(got, name)
+				case <-time.After(time.Second):
+					t.Fatalf("Got %v, the rest of the waiters did not get the lock", got)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Got order %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSequenceLock_UnlockWithoutWaiters(t *testing.T) {
+	l := &SequenceLock{}
+	l.Lock()
+	l.Unlock()
+
+	locked := make(chan struct{})
+	go func() {
+		l.Lock()
+		close(locked)
+	}()
+
+	select {
+	case <-locked:
+	case <-time.After(time.Second):
+		t.Fatal("Lock is still held after Unlock without waiters")
+	}
+}
@@ -1,10 +1,18 @@
 package daemon
 
 import (
+	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
-	"sync"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
 
+	"github.com/Alnivel/zentile/internal/protocol"
 	"github.com/Alnivel/zentile/internal/socket"
 	"github.com/Alnivel/zentile/internal/types"
 	log "github.com/sirupsen/logrus"
@@ -14,12 +22,116 @@ type Listener struct {
 	socket.Listener
 }
 
-func ListenSocket(path string) (Listener, error) {
-	listener, err := socket.Listen(path)
-	return Listener{listener}, err
+// Format of the command results sent to the client
+type ReplyFormat string
+
+const (
+	TextFormat ReplyFormat = "text" // Results are sent as is
+	JSONFormat ReplyFormat = "json" // Each result is encoded as JSON value
+)
+
+var (
+	AlreadyRunning  = errors.New("Another instance is already running")
+	UnsafeSocketDir = errors.New("Unsafe socket directory")
+)
+
+const (
+	livenessTimeout     = time.Second      // How long to wait for the reply of the instance found on the socket path
+	sequenceReadTimeout = 5 * time.Second  // How long the client in the command sequence can keep others waiting
+	maxSequenceDuration = 15 * time.Second // How long the command sequence can hold the lock in total
+	idleReadTimeout     = 10 * time.Minute // How long the connection is kept open without messages
+)
+
+// Listen on the socket path, accessible only to the user.
+// The clients sending messages longer than maxMessageLength bytes are disconnected.
+// The socket left by a crashed instance is removed,
+// but the socket of the running instance is left intact.
+func ListenSocket(path string, maxMessageLength int) (Listener, error) {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return Listener{}, err
+	}
+	// The fallback directory is in the shared temporary directory,
+	// so it could be created by another user before the daemon
+	fallbackDir := socket.FallbackRuntimeDir()
+	if strings.HasPrefix(dir, fallbackDir+string(filepath.Separator)) {
+		if err := checkPrivateDir(fallbackDir); err != nil {
+			return Listener{}, err
+		}
+		if err := checkPrivateDir(dir); err != nil {
+			return Listener{}, err
+		}
+	}
+	if err := removeStaleSocket(path); err != nil {
+		return Listener{}, err
+	}
+
+	listener, err := socket.Listen(path, maxMessageLength)
+	if err != nil {
+		return Listener{}, err
+	}
+	if err := os.Chmod(path, 0600); err != nil {
+		listener.Close()
+		return Listener{}, err
+	}
+	return Listener{listener}, nil
 }
 
-func (listener Listener) HandleIncomingCommands(commandChan chan<- CommandRequest, chanMutex *sync.Mutex) {
+// Check that the directory is owned by the user and accessible only to them
+func checkPrivateDir(dir string) error {
+	info, err := os.Lstat(dir)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%w: %v is not a directory", UnsafeSocketDir, dir)
+	}
+	if stat, ok := info.Sys().(*syscall.Stat_t); !ok || int(stat.Uid) != os.Getuid() {
+		return fmt.Errorf("%w: %v is not owned by the user", UnsafeSocketDir, dir)
+	}
+	if info.Mode().Perm() != 0700 {
+		return fmt.Errorf("%w: %v has mode %o instead of 700", UnsafeSocketDir, dir, info.Mode().Perm())
+	}
+	return nil
+}
+
+// Remove the socket if no instance replies on it
+func removeStaleSocket(path string) error {
+	info, err := os.Lstat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if info.Mode()&os.ModeSocket == 0 {
+		return fmt.Errorf("%v exists and is not a socket", path)
+	}
+
+	if isInstanceAlive(path) {
+		return fmt.Errorf("%w on %v", AlreadyRunning, path)
+	}
+
+	log.Infof("Removing stale socket %v", path)
+	return os.Remove(path)
+}
+
+// Ping the instance listening on the socket
+func isInstanceAlive(path string) bool {
+	conn, err := socket.Dial(path, socket.DEFAULT_MAX_MESSAGE_LENGTH)
+	if err != nil {
+		return false
+	}
+	defer conn.Close()
+
+	if err := conn.Send("PING"); err != nil {
+		return false
+	}
+	conn.SetReadDeadline(time.Now().Add(livenessTimeout))
+	reply, err := conn.Receive()
+	return err == nil && reply.Kind == "PONG"
+}
+
+func (listener Listener) HandleIncomingCommands(commandChan chan<- CommandRequest, lock *SequenceLock, events *EventBus) {
 	go func() {
 		defer listener.Close()
 		for {
@@ -28,38 +140,69 @@ func (listener Listener) HandleIncomingCommands(commandChan chan<- CommandReques
 				log.Warningf("Accept error: %v\n", err)
 				return
 			}
-			go handleConnection(conn, commandChan, chanMutex)
+			go handleConnection(conn, commandChan, lock, events)
 		}
 	}()
 }
 
-func handleConnection(conn socket.Conn, commandChan chan<- CommandRequest, chanMutex *sync.Mutex) {
+func handleConnection(conn socket.Conn, commandChan chan<- CommandRequest, lock *SequenceLock, events *EventBus) {
 	defer conn.Close()
 	log.Debug("Connection accepted")
 
-	chanMutex.Lock()
-	defer chanMutex.Unlock()
-	requestStartNewCommandSequence(commandChan)
-
-	for {
-		var errOnReceive, errOnSend error
-		message, errOnReceive := conn.Receive()
+	conn.SetReadDeadline(time.Now().Add(idleReadTimeout))
+	message, errOnReceive := conn.Receive()
 
-		switch errOnReceive {
-		case socket.ReadError:
-			log.Error(errOnReceive.Error())
+	// Handshake is done before anything else, including subscription
+	if errOnReceive == nil && message.Kind == "HELLO" {
+		if err := sendHello(conn, message.Args); err != nil {
+			logProtocolErr(err, message)
 			return
-		case socket.SplitTooLongError:
-			log.Error(errOnReceive.Error())
+		}
+		conn.SetReadDeadline(time.Now().Add(idleReadTimeout))
+		message, errOnReceive = conn.Receive()
+	}
+
+	// Subscribers do not send commands, so they should not block others
+	if errOnReceive == nil && message.Kind == "SUBSCRIBE" {
+		conn.SetReadDeadline(time.Time{})
+		handleSubscription(conn, message.Args, events)
+		return
+	}
+
+	sequence := connSequence{commandChan: commandChan, lock: lock}
+	defer sequence.end()
 
-			errOnSend := conn.Send("ERR", "You talking too long")
-			log.Errorf("Failed to send error to the client: %v\n", errOnSend)
+	format := TextFormat
+
+	for {
+		if errOnReceive != nil {
+			handleReceiveError(conn, errOnReceive, message, &sequence)
 			return
 		}
 
+		var errOnSend error
+
 		switch message.Kind {
 		case "PING":
 			errOnSend = conn.Send("PONG")
+		case "HELLO":
+			errOnSend = sendHello(conn, message.Args)
+		case "BEGIN":
+			sequence.begin()
+			errOnSend = conn.Send("OK")
+		case "END":
+			if err := sequence.end(); err != nil {
+				errOnSend = sendError(conn, CodeOf(err), err.Error())
+			} else {
+				errOnSend = conn.Send("OK")
+			}
+		case "FORMAT":
+			if len(message.Args) == 1 && (message.Args[0] == string(TextFormat) || message.Args[0] == string(JSONFormat)) {
+				format = ReplyFormat(message.Args[0])
+				errOnSend = conn.Send("OK")
+			} else {
+				errOnSend = sendError(conn, protocol.CodeBadArguments, "Format must be either text or json")
+			}
 		case "ACTION":
 			fallthrough
 		case "SET":
@@ -67,30 +210,202 @@ func handleConnection(conn socket.Conn, commandChan chan<- CommandRequest, chanM
 		case "QUERY":
 			fallthrough
 		case "FOR":
+			fallthrough
+		case "FOREACH":
 			if len(message.Args) >= 1 {
 				command := types.Command{
 					Kind: types.CommandType(message.Kind),
 					Name: message.Args[0],
 					Args: message.Args[1:],
 				}
+
+				// Command outside of BEGIN and END is a sequence of its own
+				implicitSequence := !sequence.open
+				if implicitSequence {
+					sequence.begin()
+				}
+
 				commandRequest, replyChan := NewCommandRequest(command)
 				commandChan <- commandRequest
-
 				result := <-replyChan
-				errOnSend = sendCommandResult(conn, command, result)
+
+				if implicitSequence {
+					if err := sequence.end(); err != nil && result.Err == nil {
+						result = types.CommandResult{Err: err}
+					}
+				}
+				errOnSend = sendCommandResult(conn, command, result, format)
 			} else {
-				errOnSend = conn.Send("ERR", "Command must have at least one argument")
+				errOnSend = sendError(conn, protocol.CodeBadArguments, "Command must have at least one argument")
 			}
+		default:
+			errOnSend = sendError(conn, protocol.CodeUnknownMessage, fmt.Sprintf("Unknown message kind %v", message.Kind))
 		}
 
-		if errOnReceive != nil {
-			logProtocolErr(errOnReceive, message)
-			return
-		}
 		if errOnSend != nil {
 			logProtocolErr(errOnSend, message)
 			return
 		}
+
+		// The client holding the lock is given less time to send the next message
+		if sequence.open {
+			conn.SetReadDeadline(sequence.readDeadline())
+		} else {
+			conn.SetReadDeadline(time.Now().Add(idleReadTimeout))
+		}
+		message, errOnReceive = conn.Receive()
+	}
+}
+
+// Command sequence of a connection, the sequence lock is held while it is open
+type connSequence struct {
+	commandChan chan<- CommandRequest
+	lock        *SequenceLock
+	open        bool
+	deadline    time.Time // The open sequence is aborted if not ended by then
+}
+
+// Begin a new sequence, ending the open one.
+// The lock is released in between, so others can run their sequences.
+func (s *connSequence) begin() {
+	if err := s.end(); err != nil {
+		log.Warnf("Failed to apply the command sequence: %v", err)
+	}
+
+	s.lock.Lock()
+	s.open = true
+	s.deadline = time.Now().Add(maxSequenceDuration)
+	requestStartNewCommandSequence(s.commandChan)
+}
+
+// Deadline of the next message of the open sequence,
+// so a client sending commands slowly does not hold the lock forever
+func (s *connSequence) readDeadline() time.Time {
+	deadline := time.Now().Add(sequenceReadTimeout)
+	if s.deadline.Before(deadline) {
+		return s.deadline
+	}
+	return deadline
+}
+
+// End the open sequence if any, applying its changes
+func (s *connSequence) end() error {
+	if !s.open {
+		return nil
+	}
+
+	err := requestEndCommandSequence(s.commandChan)
+	s.open = false
+	s.lock.Unlock()
+	return err
+}
+
+// End the open sequence if any, rolling back its changes
+func (s *connSequence) abort() {
+	if !s.open {
+		return
+	}
+
+	requestAbortCommandSequence(s.commandChan)
+	s.end()
+}
+
+func handleReceiveError(conn socket.Conn, err error, message socket.Message, sequence *connSequence) {
+	switch {
+	case errors.Is(err, os.ErrDeadlineExceeded) && sequence.open:
+		log.Warn("Client did not continue the command sequence in time, the sequence is aborted")
+		sequence.abort()
+		if err := sendError(conn, protocol.CodeAborted, "Command sequence timed out"); err != nil {
+			logProtocolErr(err, message)
+		}
+	case errors.Is(err, os.ErrDeadlineExceeded):
+		log.Debug("Closing idle connection")
+	case errors.Is(err, socket.SplitTooLongError):
+		log.Error(err.Error())
+		if err := sendError(conn, protocol.CodeBadArguments, "You talking too long"); err != nil {
+			log.Errorf("Failed to send error to the client: %v\n", err)
+		}
+	case errors.Is(err, socket.InvalidArgCountError):
+		log.Error(err.Error())
+		if err := sendError(conn, protocol.CodeBadArguments, err.Error()); err != nil {
+			log.Errorf("Failed to send error to the client: %v\n", err)
+		}
+	default:
+		logProtocolErr(err, message)
+	}
+}
+
+// Reply to HELLO message of the client with the protocol version and features of the daemon.
+// The client sends its version, it is up to the client to decide whether the versions are compatible.
+func sendHello(conn socket.Conn, args []string) error {
+	if len(args) == 0 {
+		return sendError(conn, protocol.CodeBadArguments, "HELLO must have protocol version")
+	}
+	if clientVersion, err := strconv.Atoi(args[0]); err != nil {
+		return sendError(conn, protocol.CodeBadArguments, fmt.Sprintf("Invalid protocol version %v", args[0]))
+	} else if clientVersion != protocol.VERSION {
+		log.Warnf("Client speaks protocol version %v, the daemon speaks %v", clientVersion, protocol.VERSION)
+	}
+
+	return conn.Send("HELLO", append([]string{strconv.Itoa(protocol.VERSION)}, protocol.Features...)...)
+}
+
+// Send events to the subscribed client until it closes the connection
+func handleSubscription(conn socket.Conn, args []string, events *EventBus) {
+	names := make([]protocol.EventName, len(args))
+	for i, arg := range args {
+		name, exists := parseEventName(arg)
+		if !exists {
+			err := sendError(conn, protocol.CodeBadArguments, fmt.Sprintf("Unknown event %v", arg))
+			if err != nil {
+				logProtocolErr(err, socket.Message{Kind: "SUBSCRIBE", Args: args})
+			}
+			return
+		}
+		names[i] = name
+	}
+
+	subscription := events.Subscribe(names)
+	defer subscription.Close()
+
+	if err := conn.Send("OK"); err != nil {
+		logProtocolErr(err, socket.Message{Kind: "SUBSCRIBE", Args: args})
+		return
+	}
+
+	// The client is not expected to send anything,
+	// so reading only detects the closed connection
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, err := conn.Receive(); err != nil {
+				return
+			}
+		}
+	}()
+	defer func() {
+		// Interrupt the reading and wait for it to stop before the connection is closed
+		conn.SetReadDeadline(time.Now())
+		<-closed
+	}()
+
+	for {
+		select {
+		case <-closed:
+			log.Debug("Subscriber closed the connection")
+			return
+		case event, ok := <-subscription.Events:
+			if !ok {
+				return
+			}
+
+			err := conn.Send("EVENT", append([]string{string(event.Name)}, event.Args...)...)
+			if err != nil {
+				logProtocolErr(err, socket.Message{Kind: "EVENT", Args: event.Args})
+				return
+			}
+		}
 	}
 }
 
@@ -106,8 +421,11 @@ func logProtocolErr(err error, message socket.Message) {
 	}
 }
 
-func sendCommandResult(conn socket.Conn, command types.Command, result types.CommandResult) error {
+func sendCommandResult(conn socket.Conn, command types.Command, result types.CommandResult, format ReplyFormat) error {
 	if result.Err == nil {
+		if format == JSONFormat {
+			return conn.Send("OK", encodeResultJSON(result)...)
+		}
 		return conn.Send("OK", result.Messages...)
 	} else {
 		log.Warningf(
@@ -115,6 +433,34 @@ func sendCommandResult(conn socket.Conn, command types.Command, result types.Com
 			command,
 			result.Err,
 		)
-		return conn.Send("ERR", result.Err.Error())
+		return sendError(conn, CodeOf(result.Err), result.Err.Error())
+	}
+}
+
+// Send ERR reply with the code and the text of the error
+func sendError(conn socket.Conn, code protocol.ErrorCode, text string) error {
+	return conn.Send("ERR", string(code), text)
+}
+
+// Encode each message or record of the result as JSON value
+func encodeResultJSON(result types.CommandResult) []string {
+	encoded := make([]string, 0, len(result.Messages))
+
+	var err error
+	var value []byte
+	for i, message := range result.Messages {
+		if result.Records != nil {
+			value, err = json.Marshal(result.Records[i])
+		} else {
+			value, err = json.Marshal(message)
+		}
+
+		if err != nil {
+			log.Warningf("Failed to encode result %v: %v", message, err)
+			continue
+		}
+		encoded = append(encoded, string(value))
 	}
+
+	return encoded
 }
@@ -1,18 +1,37 @@
 package daemon
 
 import (
+	"maps"
 	"slices"
 )
 
 type Store struct {
 	allowedMasters  int
 	masters, slaves []Client
+	hidden          map[Client]storeSlot // Slots of the clients that were hidden
+}
+
+// Position of a client in the store
+type storeSlot struct {
+	master bool
+	index  int
 }
 
 func buildStore() *Store {
 	return &Store{allowedMasters: 1,
 		masters: make([]Client, 0),
 		slaves:  make([]Client, 0),
+		hidden:  make(map[Client]storeSlot),
+	}
+}
+
+// Copy of the store not sharing memory with it
+func (st *Store) clone() *Store {
+	return &Store{
+		allowedMasters: st.allowedMasters,
+		masters:        slices.Clone(st.masters),
+		slaves:         slices.Clone(st.slaves),
+		hidden:         maps.Clone(st.hidden),
 	}
 }
 
@@ -25,6 +44,8 @@ func (st *Store) Add(cleint Client) {
 }
 
 func (st *Store) Remove(client Client) {
+	delete(st.hidden, client)
+
 	for i, m := range st.masters {
 		if m == client {
 			if len(st.slaves) > 0 {
@@ -45,6 +66,62 @@ func (st *Store) Remove(client Client) {
 	}
 }
 
+// Hide removes client from the store, remembering its slot
+// so it can be put back in the same place by Unhide
+func (st *Store) Hide(client Client) {
+	slot, found := st.slotOf(client)
+	if !found {
+		return
+	}
+
+	st.Remove(client)
+	st.hidden[client] = slot
+}
+
+// Unhide puts previously hidden client back to its slot.
+// Clients that were not hidden by the store are added as usual.
+func (st *Store) Unhide(client Client) {
+	slot, wasHidden := st.hidden[client]
+	if !wasHidden {
+		if _, found := st.slotOf(client); !found {
+			st.Add(client)
+		}
+		return
+	}
+
+	delete(st.hidden, client)
+	st.insert(client, slot)
+}
+
+func (st *Store) slotOf(client Client) (storeSlot, bool) {
+	if i := slices.Index(st.masters, client); i != -1 {
+		return storeSlot{master: true, index: i}, true
+	}
+	if i := slices.Index(st.slaves, client); i != -1 {
+		return storeSlot{master: false, index: i}, true
+	}
+	return storeSlot{}, false
+}
+
+func (st *Store) insert(client Client, slot storeSlot) {
+	switch {
+	case slot.master:
+		index := min(slot.index, len(st.masters))
+		st.masters = slices.Insert(st.masters, index, client)
+
+		// Demote the master that took the slot while the client was hidden
+		if mlen := len(st.masters); mlen > st.allowedMasters {
+			st.slaves = append([]Client{st.masters[mlen-1]}, st.slaves...)
+			st.masters = st.masters[:mlen-1]
+		}
+	case len(st.masters) < st.allowedMasters:
+		st.masters = append(st.masters, client)
+	default:
+		index := min(slot.index, len(st.slaves))
+		st.slaves = slices.Insert(st.slaves, index, client)
+	}
+}
+
 func removeElement(s []Client, i int) []Client {
 	return append(s[:i], s[i+1:]...)
 }
@@ -66,6 +143,16 @@ func (st *Store) DecreaseMaster() {
 	}
 }
 
+// Set number of windows allowed in the master area, moving windows between the areas
+func (st *Store) SetMasterCount(count int) {
+	clients := slices.Concat(st.masters, st.slaves)
+	split := min(count, len(clients))
+
+	st.allowedMasters = count
+	st.masters = slices.Clone(clients[:split])
+	st.slaves = slices.Clone(clients[split:])
+}
+
 func (st *Store) MakeMaster(c Client) bool {
 	for i, slave := range st.slaves {
 		if slave == c {
@@ -114,6 +201,19 @@ func (st *Store) Swap(this Client, that Client) bool {
 	return true
 }
 
+// Number of windows allowed in the master area
+func (st *Store) MasterCount() int {
+	return st.allowedMasters
+}
+
+func (st *Store) IsMaster(c Client) bool {
+	return slices.Contains(st.masters, c)
+}
+
+func (st *Store) IsSlave(c Client) bool {
+	return slices.Contains(st.slaves, c)
+}
+
 func (st *Store) All() []Client {
 	return append(st.masters, st.slaves...)
 }
@@ -0,0 +1,78 @@
+package daemon
+
+import (
+	"errors"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Transaction batches layout changes of a command sequence,
+// so each workspace is tiled at most once at the end of the sequence.
+// If a command of the sequence fails, the layouts are rolled back
+// and the rest of the sequence is not run.
+//
+// Only the state of the layouts is rolled back,
+// e.g. focus changes and marks made before the failure are kept.
+//
+// Workspaces are looked up through the tracker when the transaction ends,
+// so the removed ones are left out, and the added ones join it when created.
+type Transaction struct {
+	tracker Tracker // Not nil while the transaction is open
+	aborted bool
+}
+
+// Begin a transaction over all the workspaces, committing the open one if any
+func (tx *Transaction) Begin(tracker Tracker) {
+	if err := tx.Commit(); err != nil {
+		log.Warn(err)
+	}
+
+	tx.tracker = tracker
+	tx.eachWorkspace(func(ws *Workspace) error {
+		ws.Begin()
+		return nil
+	})
+}
+
+// Commit the open transaction if any, ending the aborted one.
+// Returns the errors of the window system while the layouts were applied.
+func (tx *Transaction) Commit() error {
+	err := tx.eachWorkspace((*Workspace).Commit)
+	tx.tracker = nil
+	tx.aborted = false
+	return err
+}
+
+// Roll back the open transaction, the commands are refused until it is ended by Commit or Begin
+func (tx *Transaction) Rollback() {
+	if !tx.IsOpen() {
+		return
+	}
+
+	if err := tx.eachWorkspace((*Workspace).Rollback); err != nil {
+		log.Warn(err)
+	}
+	tx.tracker = nil
+	tx.aborted = true
+}
+
+func (tx *Transaction) IsOpen() bool {
+	return tx != nil && tx.tracker != nil
+}
+
+func (tx *Transaction) IsAborted() bool {
+	return tx != nil && tx.aborted
+}
+
+// Call fn for the workspaces tracked now, if the transaction is open
+func (tx *Transaction) eachWorkspace(fn func(ws *Workspace) error) error {
+	if !tx.IsOpen() {
+		return nil
+	}
+
+	var errs []error
+	for num := range tx.tracker.WorkspaceCount() {
+		errs = append(errs, fn(tx.tracker.Workspace(num)))
+	}
+	return errors.Join(errs...)
+}
@@ -8,7 +8,7 @@ type VerticalLayout struct {
 	*VertHorz
 }
 
-func (l *VerticalLayout) Do() {
+func (l *VerticalLayout) Do() error {
 	log.Info("Switching to Vertical Layout")
 	wx, wy, ww, wh := l.Tracker.WorkAreaDimensions(l.WorkspaceNum)
 	msize := len(l.masters)
@@ -20,6 +20,8 @@ func (l *VerticalLayout) Do() {
 	sw := ww - mw
 	gap := l.Config.Gap
 
+	placements := make([]Placement, 0, msize+ssize)
+
 	if msize > 0 {
 		mh := (wh - (msize+1)*gap) / msize
 		if ssize == 0 {
@@ -30,7 +32,7 @@ func (l *VerticalLayout) Do() {
 			if l.Config.HideDecor {
 				c.Undecorate()
 			}
-			c.MoveResize(mx+gap, gap+wy+i*(mh+gap), mw-2*gap, mh)
+			placements = append(placements, Placement{c, Geometry{mx + gap, gap + wy + i*(mh+gap), mw - 2*gap, mh}})
 		}
 	}
 
@@ -44,18 +46,18 @@ func (l *VerticalLayout) Do() {
 			if l.Config.HideDecor {
 				c.Undecorate()
 			}
-			c.MoveResize(sx, gap+wy+i*(sh+gap), sw-gap, sh)
+			placements = append(placements, Placement{c, Geometry{sx, gap + wy + i*(sh+gap), sw - gap, sh}})
 		}
 	}
 
-	l.Tracker.Sync()
+	return l.Animator.Apply(placements)
 }
 
 type HorizontalLayout struct {
 	*VertHorz
 }
 
-func (l *HorizontalLayout) Do() {
+func (l *HorizontalLayout) Do() error {
 	log.Info("Switching to Horizontal Layout")
 	wx, wy, ww, wh := l.Tracker.WorkAreaDimensions(l.WorkspaceNum)
 	msize := len(l.masters)
@@ -67,6 +69,8 @@ func (l *HorizontalLayout) Do() {
 	sh := wh - mh
 	gap := l.Config.Gap
 
+	placements := make([]Placement, 0, msize+ssize)
+
 	if msize > 0 {
 		mw := (ww - (msize+1)*gap) / msize
 		if ssize == 0 {
@@ -77,7 +81,7 @@ func (l *HorizontalLayout) Do() {
 			if l.Config.HideDecor {
 				c.Undecorate()
 			}
-			c.MoveResize(gap+wx+i*(mw+gap), my+gap, mw, mh-2*gap)
+			placements = append(placements, Placement{c, Geometry{gap + wx + i*(mw+gap), my + gap, mw, mh - 2*gap}})
 		}
 	}
 
@@ -91,9 +95,9 @@ func (l *HorizontalLayout) Do() {
 			if l.Config.HideDecor {
 				c.Undecorate()
 			}
-			c.MoveResize(gap+wx+i*(sw+gap), sy, sw, sh-gap)
+			placements = append(placements, Placement{c, Geometry{gap + wx + i*(sw+gap), sy, sw, sh - gap}})
 		}
 	}
 
-	l.Tracker.Sync()
+	return l.Animator.Apply(placements)
 }
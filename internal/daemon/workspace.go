@@ -1,65 +1,138 @@
 package daemon
 
 import (
+	"errors"
 	"fmt"
+	"maps"
 	"slices"
+	"strconv"
 
 	"github.com/Alnivel/zentile/internal/config"
+	"github.com/Alnivel/zentile/internal/protocol"
+	log "github.com/sirupsen/logrus"
 )
 
+var InvalidMasterCount = errors.New("Number of master windows must be positive")
+
 type Workspace struct {
 	isTiling        bool
 	activeLayoutNum uint
 	layoutOrder     []string
 	layouts         map[string]Layout
+	config          *config.WorkspaceConfig
+	createLayout    func(name string) Layout
+	animator        *Animator // Shared by the layouts of the workspace
+
+	num         uint
+	events      *EventBus
+	lastMasters []Client // Masters reported in the last MasterChanged event
+
+	batch *workspaceBatch // Not nil while changes are batched
+}
+
+// Changes of a workspace postponed until the batch is committed,
+// and the state of the workspace to roll back to
+type workspaceBatch struct {
+	arrange        bool // Active layout should be applied
+	restore        bool // Clients should be restored as the workspace was untiled
+	clientsChanged bool // Clients were added or removed during the batch
+	layoutChanged  bool // LayoutChanged event should be published
+
+	isTiling        bool
+	activeLayoutNum uint
+	layoutOrder     []string
+	layouts         map[string]Layout
+	config          config.WorkspaceConfig
+	stores          map[string]*Store
+	proportions     map[string]float64
 }
 
 type WorkspaceFactory struct {
-	config *config.Config
+	config          *config.Config
+	animationFrames chan<- func()
+	events          *EventBus
+	transaction     *Transaction // Workspaces created while it is open join it
+
+	// Configs changed at runtime are kept for the workspaces created again
+	workspaceConfigs map[uint]*config.WorkspaceConfig
+}
+
+func NewWorkspaceFactory(globalConfig *config.Config, animationFrames chan<- func(), events *EventBus, transaction *Transaction) WorkspaceFactory {
+	return WorkspaceFactory{
+		config:           globalConfig,
+		animationFrames:  animationFrames,
+		events:           events,
+		transaction:      transaction,
+		workspaceConfigs: make(map[uint]*config.WorkspaceConfig),
+	}
 }
 
 func (wsf WorkspaceFactory) NewWorkspace(tracker Tracker, num uint) *Workspace {
-	workspaceConfig := wsf.config.WorkspaceConfig(num)
-
-	return &Workspace{
-		isTiling:    workspaceConfig.StartTiling,
-		layoutOrder: workspaceConfig.Layouts,
-		layouts:     wsf.createLayouts(tracker, &workspaceConfig, num),
-	}
-}
-
-func (wsf WorkspaceFactory) createLayouts(tracker Tracker, config *config.WorkspaceConfig, workspaceNum uint) map[string]Layout {
-	layouts := make(map[string]Layout, len(config.Layouts))
-
-	for _, name := range config.Layouts {
-		switch name {
-		case "vertical":
-			layouts[name] = &VerticalLayout{&VertHorz{
-				Tracker:      tracker,
-				Store:        buildStore(),
-				Proportion:   config.Proportion,
-				WorkspaceNum: workspaceNum,
-				Config:       config,
-			}}
-		case "horizontal":
-			layouts[name] = &HorizontalLayout{&VertHorz{
-				Tracker:      tracker,
-				Store:        buildStore(),
-				Proportion:   config.Proportion,
-				WorkspaceNum: workspaceNum,
-				Config:       config,
-			}}
-		case "fullscreen":
-			layouts[name] = &FullScreen{
-				Tracker:      tracker,
-				Store:        buildStore(),
-				WorkspaceNum: workspaceNum,
-				Config:       config,
-			}
-		}
+	workspaceConfig, exists := wsf.workspaceConfigs[num]
+	if !exists {
+		defaultConfig := wsf.config.WorkspaceConfig(num)
+		workspaceConfig = &defaultConfig
+		wsf.workspaceConfigs[num] = workspaceConfig
 	}
+	animator := NewAnimator(tracker, wsf.config.AnimationDuration, wsf.animationFrames)
 
-	return layouts
+	createLayout := func(name string) Layout {
+		return wsf.createLayout(name, tracker, animator, workspaceConfig, num)
+	}
+	layouts := make(map[string]Layout, len(workspaceConfig.Layouts))
+	for _, name := range workspaceConfig.Layouts {
+		layouts[name] = createLayout(name)
+	}
+
+	ws := &Workspace{
+		isTiling:     workspaceConfig.StartTiling,
+		layoutOrder:  workspaceConfig.Layouts,
+		layouts:      layouts,
+		config:       workspaceConfig,
+		createLayout: createLayout,
+		animator:     animator,
+		num:          num,
+		events:       wsf.events,
+	}
+
+	// Desktop added in the middle of a command sequence is tiled and rolled back with the others
+	if wsf.transaction.IsOpen() {
+		ws.Begin()
+	}
+	return ws
+}
+
+func (wsf WorkspaceFactory) createLayout(name string, tracker Tracker, animator *Animator, config *config.WorkspaceConfig, workspaceNum uint) Layout {
+	switch name {
+	case "vertical":
+		return &VerticalLayout{&VertHorz{
+			Tracker:      tracker,
+			Store:        buildStore(),
+			Proportion:   config.Proportion,
+			WorkspaceNum: workspaceNum,
+			Config:       config,
+			Animator:     animator,
+		}}
+	case "horizontal":
+		return &HorizontalLayout{&VertHorz{
+			Tracker:      tracker,
+			Store:        buildStore(),
+			Proportion:   config.Proportion,
+			WorkspaceNum: workspaceNum,
+			Config:       config,
+			Animator:     animator,
+		}}
+	case "fullscreen":
+		return &FullScreen{
+			Tracker:      tracker,
+			Store:        buildStore(),
+			WorkspaceNum: workspaceNum,
+			Config:       config,
+			Animator:     animator,
+		}
+	default:
+		return nil
+	}
 }
 
 func (ws *Workspace) SetLayoutByName(layoutName string) error {
@@ -69,8 +142,9 @@ func (ws *Workspace) SetLayoutByName(layoutName string) error {
 	}
 
 	ws.activeLayoutNum = uint(layoutNum)
-	ws.isTiling = true
-	ws.ActiveLayout().Do()
+	ws.setTiling(true)
+	ws.arrange()
+	ws.publishLayoutChange()
 
 	return nil
 }
@@ -90,21 +164,62 @@ func (ws *Workspace) ActiveLayout() Layout {
 // Cycle through the available layouts
 func (ws *Workspace) SwitchLayout() {
 	ws.activeLayoutNum = (ws.activeLayoutNum + 1) % uint(len(ws.layouts))
-	ws.ActiveLayout().Do()
+	ws.arrange()
+	ws.publishLayoutChange()
 }
 
 // Adds client to all the layouts in a workspace
 func (ws *Workspace) AddClient(c Client) {
-	for _, l := range ws.layouts {
-		l.Add(c)
-	}
+	ws.eachStore(func(st *Store) { st.Add(c) })
 }
 
 // Removes client from all the layouts in a workspace
 func (ws *Workspace) RemoveClient(c Client) {
+	ws.eachStore(func(st *Store) { st.Remove(c) })
+}
+
+// Removes client from all the layouts in a workspace, keeping its slot
+func (ws *Workspace) HideClient(c Client) {
+	ws.eachStore(func(st *Store) { st.Hide(c) })
+}
+
+// Puts client hidden by HideClient back to its slot in all the layouts in a workspace
+func (ws *Workspace) ShowClient(c Client) {
+	ws.eachStore(func(st *Store) { st.Unhide(c) })
+}
+
+// Applies change of the clients to the stores of all the layouts.
+// During a batch the change is applied to the stores to roll back to as well,
+// so added or removed clients are kept on rollback.
+func (ws *Workspace) eachStore(change func(st *Store)) {
 	for _, l := range ws.layouts {
-		l.Remove(c)
+		change(l.sto())
 	}
+
+	if ws.batch != nil {
+		for _, st := range ws.batch.stores {
+			change(st)
+		}
+		ws.batch.clientsChanged = true
+	}
+}
+
+// Role of the client in the active layout: master, slave or floating if it is not in the layout
+func (ws *Workspace) ClientRole(c Client) string {
+	store := ws.ActiveLayout().sto()
+	switch {
+	case store.IsMaster(c):
+		return "master"
+	case store.IsSlave(c):
+		return "slave"
+	default:
+		return "floating"
+	}
+}
+
+// Gap between windows in the workspace
+func (ws *Workspace) Gap() int {
+	return ws.config.Gap
 }
 
 // Is the workspace tiling
@@ -115,12 +230,264 @@ func (ws *Workspace) IsTiling() bool {
 // Tiles the active layout in a workspace
 func (ws *Workspace) Tile() {
 	if ws.isTiling {
-		ws.ActiveLayout().Do()
+		ws.arrange()
 	}
+	ws.publishMasterChange()
 }
 
 // Untiles the active layout in a workspace.
 func (ws *Workspace) Untile() {
-	ws.isTiling = false
-	ws.ActiveLayout().Undo()
+	ws.setTiling(false)
+	if ws.batch != nil {
+		ws.batch.restore, ws.batch.arrange = true, false
+		return
+	}
+	if err := ws.ActiveLayout().Undo(); err != nil {
+		log.Warn(err)
+	}
+}
+
+// Stops the animation in progress, called before the workspace is removed.
+// The clients are left where they are, as they are moved to another workspace.
+func (ws *Workspace) Destroy() {
+	ws.batch = nil
+	ws.animator.Cancel()
+}
+
+// Applies the active layout, or postpones it until the commit during a batch
+func (ws *Workspace) arrange() {
+	if ws.batch != nil {
+		ws.batch.arrange, ws.batch.restore = true, false
+		return
+	}
+	if err := ws.ActiveLayout().Do(); err != nil {
+		log.Warn(err)
+	}
+}
+
+// Begin batching changes of the workspace, so the layout is applied once on Commit
+// and the layouts can be brought back to the current state on Rollback
+func (ws *Workspace) Begin() {
+	if ws.batch != nil {
+		if err := ws.Commit(); err != nil {
+			log.Warn(err)
+		}
+	}
+
+	batch := &workspaceBatch{
+		isTiling:        ws.isTiling,
+		activeLayoutNum: ws.activeLayoutNum,
+		layoutOrder:     ws.layoutOrder,
+		layouts:         maps.Clone(ws.layouts),
+		config:          *ws.config,
+		stores:          make(map[string]*Store, len(ws.layouts)),
+		proportions:     make(map[string]float64, len(ws.layouts)),
+	}
+	for name, l := range ws.layouts {
+		batch.stores[name] = l.sto().clone()
+		batch.proportions[name] = l.GetProportion()
+	}
+	ws.batch = batch
+}
+
+// Apply the changes made since Begin
+func (ws *Workspace) Commit() error {
+	batch := ws.batch
+	if batch == nil {
+		return nil
+	}
+	ws.batch = nil
+
+	var err error
+	switch {
+	case batch.restore:
+		err = ws.ActiveLayout().Undo()
+	case batch.arrange:
+		err = ws.ActiveLayout().Do()
+	}
+
+	// Subscribers only see the state the sequence ended with
+	if ws.isTiling != batch.isTiling {
+		ws.publishTilingToggle()
+	}
+	if batch.layoutChanged {
+		ws.publishLayoutChange()
+	} else {
+		ws.publishMasterChange()
+	}
+	return err
+}
+
+// Discard the changes made since Begin, except the added and removed clients
+func (ws *Workspace) Rollback() error {
+	batch := ws.batch
+	if batch == nil {
+		return nil
+	}
+	ws.batch = nil
+
+	*ws.config = batch.config
+	ws.layoutOrder = batch.layoutOrder
+	ws.layouts = batch.layouts
+	ws.activeLayoutNum = batch.activeLayoutNum
+	for name, l := range ws.layouts {
+		*l.sto() = *batch.stores[name]
+		l.SetProportion(batch.proportions[name])
+	}
+
+	// The events of the batch were not published, so there is nothing to revert
+	ws.isTiling = batch.isTiling
+
+	// The windows were not moved during the batch, unless there are new ones to place
+	var err error
+	if batch.clientsChanged && ws.isTiling {
+		err = ws.ActiveLayout().Do()
+	}
+	ws.publishMasterChange()
+	return err
+}
+
+// Set gap between windows of the workspace
+func (ws *Workspace) SetGap(gap int) error {
+	if err := config.ValidateGap(gap); err != nil {
+		return err
+	}
+
+	ws.config.Gap = gap
+	ws.Tile()
+	return nil
+}
+
+// Set proportion of the master area in all the layouts of the workspace
+func (ws *Workspace) SetProportion(proportion float64) error {
+	if err := config.ValidateProportion(proportion); err != nil {
+		return err
+	}
+
+	ws.config.Proportion = proportion
+	for _, l := range ws.layouts {
+		l.SetProportion(proportion)
+	}
+	ws.Tile()
+	return nil
+}
+
+// Set number of master windows in all the layouts of the workspace
+func (ws *Workspace) SetMasterCount(count int) error {
+	if count < 1 {
+		return fmt.Errorf("%w: %v", InvalidMasterCount, count)
+	}
+
+	for _, l := range ws.layouts {
+		l.sto().SetMasterCount(count)
+	}
+	ws.Tile()
+	return nil
+}
+
+// Set whether decorations of the windows are removed while tiling
+func (ws *Workspace) SetHideDecorations(hide bool) {
+	ws.config.HideDecor = hide
+	if !hide && ws.isTiling {
+		for _, c := range ws.ActiveLayout().sto().All() {
+			c.Decorate()
+		}
+	}
+	ws.Tile()
+}
+
+// Set whether the workspace is tiling when it is created,
+// it takes effect when the desktop is removed and created again
+func (ws *Workspace) SetStartTiling(startTiling bool) {
+	ws.config.StartTiling = startTiling
+}
+
+// Replace the layouts of the workspace. Kept layouts preserve their state,
+// the new ones start with the windows ordered as in the active layout.
+func (ws *Workspace) SetLayouts(names []string) error {
+	names, err := config.ValidateLayouts(names)
+	if err != nil {
+		return err
+	}
+
+	activeLayoutName := ws.ActiveLayoutName()
+	activeStore := ws.ActiveLayout().sto()
+
+	layouts := make(map[string]Layout, len(names))
+	for _, name := range names {
+		if l, exists := ws.layouts[name]; exists {
+			layouts[name] = l
+			continue
+		}
+
+		l := ws.createLayout(name)
+		*l.sto() = *activeStore.clone()
+		layouts[name] = l
+	}
+
+	ws.config.Layouts = names
+	ws.layoutOrder = names
+	ws.layouts = layouts
+
+	activeLayoutNum := slices.Index(names, activeLayoutName)
+	if activeLayoutNum == -1 {
+		ws.activeLayoutNum = 0
+		ws.Tile()
+		ws.publishLayoutChange()
+	} else {
+		ws.activeLayoutNum = uint(activeLayoutNum)
+	}
+	return nil
+}
+
+func (ws *Workspace) setTiling(isTiling bool) {
+	if ws.isTiling == isTiling {
+		return
+	}
+
+	ws.isTiling = isTiling
+	if ws.batch == nil {
+		ws.publishTilingToggle()
+	}
+}
+
+func (ws *Workspace) publishTilingToggle() {
+	ws.events.Publish(protocol.TilingToggled, ws.numString(), strconv.FormatBool(ws.isTiling))
+}
+
+// Publishes LayoutChanged event, during a batch it is postponed until the commit
+func (ws *Workspace) publishLayoutChange() {
+	if ws.batch != nil {
+		ws.batch.layoutChanged = true
+		return
+	}
+
+	ws.events.Publish(protocol.LayoutChanged, ws.numString(), ws.ActiveLayoutName())
+	ws.publishMasterChange()
+}
+
+// Publishes MasterChanged event if masters of the active layout
+// have changed since the last published event.
+// During a batch the event is postponed until the commit.
+func (ws *Workspace) publishMasterChange() {
+	if ws.batch != nil {
+		return
+	}
+
+	masters := ws.ActiveLayout().sto().masters
+	if slices.Equal(masters, ws.lastMasters) {
+		return
+	}
+	ws.lastMasters = slices.Clone(masters)
+
+	args := make([]string, 0, len(masters)+1)
+	args = append(args, ws.numString())
+	for _, master := range masters {
+		args = append(args, master.Id().String())
+	}
+	ws.events.Publish(protocol.MasterChanged, args...)
+}
+
+func (ws *Workspace) numString() string {
+	return strconv.FormatUint(uint64(ws.num), 10)
 }
@@ -0,0 +1,70 @@
+package daemon
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var (
+	UnknownWorkspace   = errors.New("Unknown workspace")
+	MultipleWorkspaces = errors.New("Several workspaces cannot be the target")
+)
+
+// Symbolic workspaces, accepted wherever workspace number is accepted
+var WorkspaceTargets = []string{"current", "next", "prev", "last", "all"}
+
+// Resolve workspace to the workspace numbers, only "all" resolves to several of them:
+//   - current is the workspace shown on the screen
+//   - next and prev are the workspaces following and preceding the target one, wrapping around
+//   - last is the workspace visited before the current one
+//   - all are all the workspaces
+//
+// Otherwise the workspace is either a number or a name of the desktop.
+func parseWorkspaces(arg string, ctx *CommandContext, tr Tracker) ([]uint, error) {
+	count := tr.WorkspaceCount()
+
+	switch arg {
+	case "current":
+		return []uint{tr.CurentWorkspaceNum()}, nil
+	case "next":
+		return []uint{(ctx.TargetWorkspaceNum + 1) % count}, nil
+	case "prev":
+		return []uint{(ctx.TargetWorkspaceNum + count - 1) % count}, nil
+	case "last":
+		return []uint{tr.LastWorkspaceNum()}, nil
+	case "all":
+		nums := make([]uint, count)
+		for num := range count {
+			nums[num] = num
+		}
+		return nums, nil
+	}
+
+	if workspaceNum, err := strconv.ParseUint(arg, 10, 64); err == nil {
+		if workspaceNum >= uint64(count) {
+			return nil, fmt.Errorf("%w: %v, there are %d workspaces", WorkspaceOutOfRange, arg, count)
+		}
+		return []uint{uint(workspaceNum)}, nil
+	}
+
+	for num := range count {
+		if arg != "" && tr.WorkspaceName(num) == arg {
+			return []uint{num}, nil
+		}
+	}
+	return nil, fmt.Errorf("%w \"%v\": expected number, name or one of %v", UnknownWorkspace, arg, strings.Join(WorkspaceTargets, ", "))
+}
+
+// Resolve workspace to the single workspace number, see parseWorkspaces
+func parseWorkspace(arg string, ctx *CommandContext, tr Tracker) (uint, error) {
+	nums, err := parseWorkspaces(arg, ctx, tr)
+	if err != nil {
+		return 0, err
+	}
+	if len(nums) != 1 {
+		return 0, fmt.Errorf("%w \"%v\": use foreach workspace to run commands for each of them", MultipleWorkspaces, arg)
+	}
+	return nums[0], nil
+}
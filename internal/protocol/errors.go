@@ -0,0 +1,67 @@
+package protocol
+
+import (
+	"errors"
+
+	"github.com/Alnivel/zentile/internal/socket"
+)
+
+// Categories of the errors, each one is reported with its code
+var (
+	UnknownCommand      = errors.New("Unknown command")
+	BadArguments        = errors.New("Bad arguments")
+	WindowNotFound      = errors.New("Window not found")
+	WorkspaceOutOfRange = errors.New("Workspace number is out of range")
+	NotTiling           = errors.New("Target workspace is not tiling")
+	BackendFailure      = errors.New("Window system request failed")
+	SequenceAborted     = errors.New("Command sequence was aborted by an earlier error")
+)
+
+// Stable code of the error, sent to the client as the first argument of ERR reply
+type ErrorCode string
+
+const (
+	CodeUnknownCommand      ErrorCode = "unknown_command"
+	CodeBadArguments        ErrorCode = "bad_arguments"
+	CodeWindowNotFound      ErrorCode = "window_not_found"
+	CodeWorkspaceOutOfRange ErrorCode = "workspace_out_of_range"
+	CodeNotTiling           ErrorCode = "not_tiling"
+	CodeBackendFailure      ErrorCode = "backend_failure"
+	CodeAborted             ErrorCode = "aborted"         // An earlier command of the sequence failed
+	CodeUnknownMessage      ErrorCode = "unknown_message" // Message of the protocol the daemon does not know
+	CodeFailed              ErrorCode = "failed"          // Error of no other category
+)
+
+// Sentinel error of the code, nil for CodeFailed, CodeUnknownMessage and unknown codes
+func ErrorOfCode(code ErrorCode) error {
+	switch code {
+	case CodeUnknownCommand:
+		return UnknownCommand
+	case CodeBadArguments:
+		return BadArguments
+	case CodeWindowNotFound:
+		return WindowNotFound
+	case CodeWorkspaceOutOfRange:
+		return WorkspaceOutOfRange
+	case CodeNotTiling:
+		return NotTiling
+	case CodeBackendFailure:
+		return BackendFailure
+	case CodeAborted:
+		return SequenceAborted
+	default:
+		return nil
+	}
+}
+
+// Code and text of ERR reply, the daemons without error codes send only the text
+func ReplyError(reply socket.Message) (ErrorCode, string) {
+	switch len(reply.Args) {
+	case 0:
+		return CodeFailed, "Unknown error"
+	case 1:
+		return CodeFailed, reply.Args[0]
+	default:
+		return ErrorCode(reply.Args[0]), reply.Args[1]
+	}
+}
@@ -0,0 +1,24 @@
+package protocol
+
+// Name of the event sent to the subscribers in EVENT message
+type EventName string
+
+const (
+	WorkspaceChanged EventName = "workspace_changed" // Args: workspace number
+	LayoutChanged    EventName = "layout_changed"    // Args: workspace number, layout name
+	TilingToggled    EventName = "tiling_toggled"    // Args: workspace number, whether it is tiling
+	WindowAdded      EventName = "window_added"      // Args: window id, workspace number
+	WindowRemoved    EventName = "window_removed"    // Args: window id
+	WindowFocused    EventName = "window_focused"    // Args: window id
+	MasterChanged    EventName = "master_changed"    // Args: workspace number, master window ids
+)
+
+var EventNames = []EventName{
+	WorkspaceChanged,
+	LayoutChanged,
+	TilingToggled,
+	WindowAdded,
+	WindowRemoved,
+	WindowFocused,
+	MasterChanged,
+}
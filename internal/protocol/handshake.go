@@ -0,0 +1,41 @@
+package protocol
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/Alnivel/zentile/internal/socket"
+)
+
+var IncompatibleDaemon = errors.New("Incompatible daemon")
+
+// Handshake exchanges HELLO messages with the daemon and checks that it speaks the same protocol version.
+// Daemons older than the handshake do not reply to it, so the caller sets the read deadline,
+// the reply not received by then is reported as IncompatibleDaemon wrapping os.ErrDeadlineExceeded.
+func Handshake(conn *socket.Conn) error {
+	err := conn.Send("HELLO", append([]string{strconv.Itoa(VERSION)}, Features...)...)
+	if err != nil {
+		return err
+	}
+
+	reply, err := conn.Receive()
+	if errors.Is(err, os.ErrDeadlineExceeded) {
+		return fmt.Errorf("%w: the daemon does not support handshake, please restart it: %w", IncompatibleDaemon, err)
+	} else if err != nil {
+		return err
+	}
+	if reply.Kind != "HELLO" || len(reply.Args) == 0 {
+		return fmt.Errorf("%w: unexpected reply to handshake %v, please restart the daemon", IncompatibleDaemon, reply)
+	}
+
+	daemonVersion, err := strconv.Atoi(reply.Args[0])
+	if err != nil || daemonVersion != VERSION {
+		return fmt.Errorf(
+			"%w: the daemon is version %v, the client is version %v, please restart the daemon",
+			IncompatibleDaemon, reply.Args[0], VERSION,
+		)
+	}
+	return nil
+}
@@ -0,0 +1,11 @@
+// Package protocol defines the parts of the socket protocol shared by the daemon and its clients,
+// along with the helpers used by the clients.
+// It does not depend on the daemon, so the clients do not pull in the window system.
+package protocol
+
+// Version of the protocol, incremented on incompatible changes.
+// Clients send it in HELLO message and expect the daemon to reply with the same version.
+const VERSION = 2
+
+// Optional parts of the protocol the daemon supports, sent in HELLO reply
+var Features = []string{"begin", "end", "format", "subscribe", "foreach", "error_codes"}
@@ -1,33 +1,48 @@
 package socket
 
 import (
+	"fmt"
 	"iter"
 	"net"
 	"strconv"
+	"time"
 )
 
 type Conn struct {
-	conn         net.Conn
-	readIterNext func() ([]byte, error, bool)
-	readIterStop func()
+	conn             net.Conn
+	readIterNext     func() ([]byte, error, bool)
+	readIterStop     func()
+	maxMessageLength int
 }
 
-func Dial(path string) (Conn, error) {
+// Dial connects to the socket, the received messages are limited to maxMessageLength bytes
+func Dial(path string, maxMessageLength int) (Conn, error) {
 	conn, err := net.Dial("unix", path)
 	if err != nil {
 		return Conn{}, err
 	}
 
-	return NewSocketConn(conn), nil
+	return NewSocketConnWithLimit(conn, maxMessageLength), nil
 }
 
 func NewSocketConn(conn net.Conn) Conn {
-	readIterNext, readIterStop := iter.Pull2(readSplitSeq(conn, []byte{0}))
+	return NewSocketConnWithLimit(conn, DEFAULT_MAX_MESSAGE_LENGTH)
+}
+
+// NewSocketConnWithLimit creates connection receiving messages up to maxMessageLength bytes long,
+// counting the separators. A single argument can take the whole length.
+// The limit is DEFAULT_MAX_MESSAGE_LENGTH if maxMessageLength is not positive, e.g. unset in the config.
+func NewSocketConnWithLimit(conn net.Conn, maxMessageLength int) Conn {
+	if maxMessageLength <= 0 {
+		maxMessageLength = DEFAULT_MAX_MESSAGE_LENGTH
+	}
+	readIterNext, readIterStop := iter.Pull2(readSplitSeq(conn, []byte{0}, maxMessageLength))
 
 	return Conn{
-		conn:         conn,
-		readIterNext: readIterNext,
-		readIterStop: readIterStop,
+		conn:             conn,
+		readIterNext:     readIterNext,
+		readIterStop:     readIterStop,
+		maxMessageLength: maxMessageLength,
 	}
 }
 
@@ -37,6 +52,11 @@ func (s *Conn) Close() {
 
 }
 
+// SetReadDeadline sets the deadline for the future and pending reads, see net.Conn
+func (s *Conn) SetReadDeadline(t time.Time) error {
+	return s.conn.SetReadDeadline(t)
+}
+
 func (s *Conn) Read() (string, error) {
 	splitBuf, error, done := s.readIterNext()
 	_ = done
@@ -72,6 +92,8 @@ func (s *Conn) SendMessage(message Message) error {
 	return s.Send(message.Kind, message.Args...)
 }
 
+// Receive reads the next message. A message over the limit yields SplitTooLongError,
+// the connection cannot be read after it or any other error.
 func (s *Conn) Receive() (Message, error) {
 	kind, err := s.Read()
 	if err != nil {
@@ -84,20 +106,32 @@ func (s *Conn) Receive() (Message, error) {
 	}
 
 	argc, err := strconv.Atoi(argcStr)
-	if err != nil {
-		return Message{}, err
+	if err != nil || argc < 0 {
+		return Message{}, fmt.Errorf("%w: %q", InvalidArgCountError, argcStr)
+	}
+
+	// Every argument takes at least its separator
+	length := len(kind) + len(argcStr) + 2
+	if argc > s.maxMessageLength-length {
+		return Message{}, SplitTooLongError
 	}
 
-	args := make([]string, argc)
-	for i := range argc {
-		args[i], err = s.Read()
+	args := make([]string, 0, min(argc, READ_BUFFER_LENGTH))
+	for range argc {
+		arg, err := s.Read()
 		if err != nil {
 			return Message{}, err
 		}
+
+		length += len(arg) + 1
+		if length > s.maxMessageLength {
+			return Message{}, SplitTooLongError
+		}
+		args = append(args, arg)
 	}
 
 	return Message{
 		Kind: kind,
 		Args: args,
-	}, err
+	}, nil
 }
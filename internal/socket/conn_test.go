@@ -1,9 +1,11 @@
 package socket_test
 
 import (
+	"errors"
 	"io"
 	"net"
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/Alnivel/zentile/internal/socket"
@@ -112,6 +114,18 @@ func TestConn_SendReceivePair(t *testing.T) {
 				{"NextMessage", []string{}},
 			},
 		},
+		{
+			// E.g. query windows on a busy desktop
+			name: "MultiKilobyteMessages",
+			input: []socket.Message{
+				{"OK", []string{strings.Repeat("window\t", 2000), "short", strings.Repeat("x", 70000)}},
+				{"NextMessage", []string{}},
+			},
+			want: []socket.Message{
+				{"OK", []string{strings.Repeat("window\t", 2000), "short", strings.Repeat("x", 70000)}},
+				{"NextMessage", []string{}},
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -159,3 +173,68 @@ func TestConn_SendReceivePair(t *testing.T) {
 		})
 	}
 }
+
+func TestConn_ReceiveLimits(t *testing.T) {
+	const limit = 64
+
+	tests := []struct {
+		name string
+
+		input   []string // Written as is, each followed by the separator
+		want    socket.Message
+		wantErr error
+	}{
+		{
+			name:  "WithinLimit",
+			input: []string{"ACTION", "2", "swap", strings.Repeat("x", limit-len("ACTION2swap")-5)},
+			want:  socket.Message{Kind: "ACTION", Args: []string{"swap", strings.Repeat("x", limit-len("ACTION2swap")-5)}},
+		},
+		{
+			name:    "NegativeArgCount",
+			input:   []string{"ACTION", "-1"},
+			wantErr: socket.InvalidArgCountError,
+		},
+		{
+			name:    "NotNumberArgCount",
+			input:   []string{"ACTION", "two"},
+			wantErr: socket.InvalidArgCountError,
+		},
+		{
+			name:    "ArgCountOverLimit",
+			input:   []string{"ACTION", "1000000000"},
+			wantErr: socket.SplitTooLongError,
+		},
+		{
+			name:    "ArgsOverLimit",
+			input:   []string{"ACTION", "3", strings.Repeat("x", 30), strings.Repeat("y", 30), "z"},
+			wantErr: socket.SplitTooLongError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pipeConnA, pipeConnB := net.Pipe()
+
+			socketConnA := socket.NewSocketConn(pipeConnA)
+			socketConnB := socket.NewSocketConnWithLimit(pipeConnB, limit)
+			defer socketConnA.Close()
+			defer socketConnB.Close()
+
+			// Write in background, the writes after the reader gave up fail
+			go func() {
+				for _, str := range tt.input {
+					if socketConnA.Write(str) != nil {
+						return
+					}
+				}
+			}()
+
+			got, err := socketConnB.Receive()
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Got error %v, expecting %v", err, tt.wantErr)
+			}
+			if err == nil && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
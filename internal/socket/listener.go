@@ -5,21 +5,24 @@ import (
 )
 
 type Listener struct {
-	listener net.Listener
+	listener         net.Listener
+	maxMessageLength int
 }
 
-func Listen(path string) (Listener, error) {
+// Listen on the socket, the messages received by the accepted connections are limited to maxMessageLength bytes
+func Listen(path string, maxMessageLength int) (Listener, error) {
 	listener, err := net.Listen("unix", path)
 	if err != nil {
 		return Listener{}, err
 	}
 
-	return NewSocketListener(listener), nil
+	return NewSocketListener(listener, maxMessageLength), nil
 }
 
-func NewSocketListener(listener net.Listener) Listener {
+func NewSocketListener(listener net.Listener, maxMessageLength int) Listener {
 	return Listener{
-		listener: listener,
+		listener:         listener,
+		maxMessageLength: maxMessageLength,
 	}
 }
 
@@ -32,5 +35,5 @@ func (sl *Listener) Accept() (Conn, error) {
 	if err != nil {
 		return Conn{}, err
 	}
-	return NewSocketConn(conn), nil
+	return NewSocketConnWithLimit(conn, sl.maxMessageLength), nil
 }
@@ -0,0 +1,41 @@
+package socket
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Environment variable overriding the default socket path
+const PATH_ENV = "ZENTILE_SOCKET"
+
+// DefaultPath returns the socket path of the instance managing the current X display.
+// It is $ZENTILE_SOCKET if set, otherwise $XDG_RUNTIME_DIR/zentile/<DISPLAY>.sock.
+// Without XDG_RUNTIME_DIR the socket is placed in the per user directory in the temporary directory.
+func DefaultPath() string {
+	if path := os.Getenv(PATH_ENV); path != "" {
+		return path
+	}
+
+	runtimeDir := os.Getenv("XDG_RUNTIME_DIR")
+	if runtimeDir == "" {
+		runtimeDir = FallbackRuntimeDir()
+	}
+
+	return filepath.Join(runtimeDir, "zentile", displayFileName(os.Getenv("DISPLAY"))+".sock")
+}
+
+// FallbackRuntimeDir returns the per user directory in the temporary directory,
+// used in place of XDG_RUNTIME_DIR if it is not set
+func FallbackRuntimeDir() string {
+	return filepath.Join(os.TempDir(), fmt.Sprintf("zentile-%d", os.Getuid()))
+}
+
+// Name of the display usable as a file name, e.g. ":0" or "localhost:10.0"
+func displayFileName(display string) string {
+	if display == "" {
+		return "default"
+	}
+	return strings.ReplaceAll(display, string(filepath.Separator), "_")
+}
@@ -0,0 +1,38 @@
+package socket
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDefaultPath(t *testing.T) {
+	tests := []struct {
+		name string
+
+		socketEnv  string
+		runtimeDir string
+		display    string
+		want       string
+	}{
+		{"PerDisplay", "", "/run/user/1000", ":1", "/run/user/1000/zentile/:1.sock"},
+		{"RemoteDisplay", "", "/run/user/1000", "localhost:10.0", "/run/user/1000/zentile/localhost:10.0.sock"},
+		{"DisplayWithSlashes", "", "/run/user/1000", "/tmp/launch/org.x:0", "/run/user/1000/zentile/_tmp_launch_org.x:0.sock"},
+		{"NoDisplay", "", "/run/user/1000", "", "/run/user/1000/zentile/default.sock"},
+		{"Override", "/tmp/custom.sock", "/run/user/1000", ":0", "/tmp/custom.sock"},
+		{"NoRuntimeDir", "", "", ":0", filepath.Join(os.TempDir(), fmt.Sprintf("zentile-%d", os.Getuid()), "zentile", ":0.sock")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(PATH_ENV, tt.socketEnv)
+			t.Setenv("XDG_RUNTIME_DIR", tt.runtimeDir)
+			t.Setenv("DISPLAY", tt.display)
+
+			if got := DefaultPath(); got != tt.want {
+				t.Errorf("DefaultPath() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -8,54 +8,70 @@ import (
 )
 
 var (
-	ReadError         = errors.New("Failed to read from connection")
-	SplitTooLongError = errors.New("Message is longer than the read buffer")
+	ReadError             = errors.New("Failed to read from connection")
+	SplitTooLongError     = errors.New("Message is longer than the limit")
 	InvalidSeparatorError = errors.New("Separator cannot be zero length")
+	InvalidArgCountError  = errors.New("Invalid number of message arguments")
 )
 
-func readSplitSeq(conn net.Conn, sep []byte) iter.Seq2[[]byte, error] {
-	return func(yield func([]byte, error) bool) {
-		readBuf := make([]byte, 512)
-		splitBuf := make([]byte, 0, 512)
+// Size of a single read from the connection
+const READ_BUFFER_LENGTH = 512
+
+// Default limit of the length of a message, including the separators
+const DEFAULT_MAX_MESSAGE_LENGTH = 4 << 20
 
+// Read the connection and yield the data between separators.
+// The buffer grows as needed up to maxSplitLength bytes per split,
+// a longer split yields SplitTooLongError for this and all the following reads.
+// The yielded slice is valid only until the next iteration.
+func readSplitSeq(conn net.Conn, sep []byte, maxSplitLength int) iter.Seq2[[]byte, error] {
+	return func(yield func([]byte, error) bool) {
 		sepLen := len(sep)
 		if sepLen == 0 {
 			yield(nil, InvalidSeparatorError)
 			return
 		}
-	ReadLoop:
+
+		readBuf := make([]byte, READ_BUFFER_LENGTH)
+		pending := make([]byte, 0, READ_BUFFER_LENGTH)
+		searchFrom := 0 // No separator starts before it in the pending data
+
 		for {
 			n, err := conn.Read(readBuf)
-			if err != nil {
-				if !yield(nil, err) {
+			pending = append(pending, readBuf[:n]...)
+
+			for {
+				sepIndex := bytes.Index(pending[searchFrom:], sep)
+				if sepIndex == -1 {
+					break
+				}
+
+				splitEnd := searchFrom + sepIndex
+				if splitEnd > maxSplitLength {
+					break
+				}
+				if !yield(pending[:splitEnd], nil) {
 					return
 				}
+				pending = pending[splitEnd+sepLen:]
+				searchFrom = 0
 			}
 
-			for split := range bytes.SplitSeq(readBuf[:n], sep) {
-				n -= len(split) + sepLen // Account for the separator
-
-				if cap(splitBuf) < len(splitBuf)+len(split) {
+			// The end of the pending data can be the beginning of the separator
+			searchFrom = max(0, len(pending)-(sepLen-1))
+			if searchFrom > maxSplitLength {
+				for {
 					if !yield(nil, SplitTooLongError) {
 						return
 					}
 				}
+			}
 
-				splitBuf = append(splitBuf, split...)
-
-				if n < 0 { // If there was no separator
-					// i.e. the command is split between reads
-					continue ReadLoop
-					// The current split is the last in the current readBuf
-					// So breaking inner or continuing outer loop is the same
-				}
-
-				if !yield(splitBuf, nil) {
+			if err != nil {
+				if !yield(nil, err) {
 					return
 				}
-				splitBuf = splitBuf[:0] // Reset the buffer
 			}
 		}
 	}
 }
-
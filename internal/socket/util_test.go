@@ -53,7 +53,7 @@ func Test_readSplitSeq(t *testing.T) {
 			got := make([]string, 0)
 			var gotErr error = nil
 
-			for split, splitErr := range readSplitSeq(pipeOut, tt.sep) {
+			for split, splitErr := range readSplitSeq(pipeOut, tt.sep, 512) {
 				if splitErr != nil {
 					gotErr = splitErr
 					break
@@ -74,10 +74,9 @@ func Test_readSplitSeq(t *testing.T) {
 }
 
 func Test_readSplitSeq_longData(t *testing.T) {
-	// The read buffer and the max allowed size of slice are (hardcoded) 512 bytes
-	const readBufferLength int = 512
+	// The read buffer is 512 bytes and the splits are limited to 512 bytes as well
+	const readBufferLength int = READ_BUFFER_LENGTH
 	longStr := strings.Repeat("A", readBufferLength+20)
-	// The read buffer is capped at 512 bytes too
 	maxLengthData := []string{"One", "Two", longStr[:readBufferLength], "Four"}
 
 	tooLongData := []string{"One", "Two", longStr[:], "Four"}
@@ -145,7 +144,7 @@ func Test_readSplitSeq_longData(t *testing.T) {
 			got := make([]string, 0)
 			var gotErr error = nil
 
-			for split, splitErr := range readSplitSeq(pipeOut, tt.sep) {
+			for split, splitErr := range readSplitSeq(pipeOut, tt.sep, 512) {
 				if splitErr != nil {
 					gotErr = splitErr
 					break
@@ -164,3 +163,107 @@ func Test_readSplitSeq_longData(t *testing.T) {
 		})
 	}
 }
+
+func Test_readSplitSeq_multiKilobyte(t *testing.T) {
+	// Distinct bytes, so misplaced chunks are detected
+	payload := func(length int) string {
+		b := make([]byte, length)
+		for i := range b {
+			b[i] = 'a' + byte(i%26)
+		}
+		return string(b)
+	}
+
+	tests := []struct {
+		name string
+
+		input   []string
+		sep     []byte
+		limit   int
+		want    []string
+		wantErr error
+	}{
+		{
+			name:    "SeveralKilobytes",
+			input:   []string{payload(4096), "short", payload(10000)},
+			sep:     []byte{0},
+			limit:   DEFAULT_MAX_MESSAGE_LENGTH,
+			want:    []string{payload(4096), "short", payload(10000)},
+			wantErr: io.EOF,
+		},
+		{
+			name:    "MultiByteSeparator",
+			input:   []string{payload(3000), payload(5000)},
+			sep:     []byte("|separator|"),
+			limit:   DEFAULT_MAX_MESSAGE_LENGTH,
+			want:    []string{payload(3000), payload(5000)},
+			wantErr: io.EOF,
+		},
+		{
+			// The separator starts at the last byte of the first read
+			name:    "SeparatorAtReadBoundary",
+			input:   []string{payload(READ_BUFFER_LENGTH*4 - 1), "end"},
+			sep:     []byte("|separator|"),
+			limit:   DEFAULT_MAX_MESSAGE_LENGTH,
+			want:    []string{payload(READ_BUFFER_LENGTH*4 - 1), "end"},
+			wantErr: io.EOF,
+		},
+		{
+			name:    "ExactlyAtLimit",
+			input:   []string{payload(8192), "end"},
+			sep:     []byte{0},
+			limit:   8192,
+			want:    []string{payload(8192), "end"},
+			wantErr: io.EOF,
+		},
+		{
+			name:    "OverLimit",
+			input:   []string{"first", payload(8193), "end"},
+			sep:     []byte{0},
+			limit:   8192,
+			want:    []string{"first"},
+			wantErr: SplitTooLongError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			pipeIn, pipeOut := net.Pipe()
+			defer pipeOut.Close()
+
+			// Simulate sending data in one write
+			go func() {
+				defer pipeIn.Close()
+				buf := new(bytes.Buffer)
+				for _, split := range tt.input {
+					buf.Write([]byte(split))
+					buf.Write(tt.sep)
+				}
+				pipeIn.Write(buf.Bytes())
+			}()
+
+			// Collect results
+			got := make([]string, 0)
+			var gotErr error = nil
+
+			for split, splitErr := range readSplitSeq(pipeOut, tt.sep, tt.limit) {
+				if splitErr != nil {
+					gotErr = splitErr
+					break
+				}
+
+				got = append(got, string(split))
+			}
+
+			// Verify
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %d results, want %d", len(got), len(tt.want))
+			}
+			if !errors.Is(gotErr, tt.wantErr) {
+				t.Errorf("got error %v, want %v", gotErr, tt.wantErr)
+			}
+		})
+	}
+}
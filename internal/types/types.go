@@ -1,5 +1,11 @@
 package types
 
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+)
+
 type CommandType string
 
 const (
@@ -7,6 +13,8 @@ const (
 	Set    CommandType = "SET"
 	Query  CommandType = "QUERY"
 	For    CommandType = "FOR"
+	// Runs the block of commands, passed as the last argument, for each window or workspace
+	Foreach CommandType = "FOREACH"
 )
 
 type Command struct {
@@ -15,8 +23,81 @@ type Command struct {
 	Args []string
 }
 
+// Type of a command argument
+type ArgType string
+
+const (
+	ArgWindow    ArgType = "WID"
+	ArgWorkspace ArgType = "workspace"
+	ArgMark      ArgType = "mark"
+	ArgInt       ArgType = "int"
+	ArgFloat     ArgType = "float"
+	ArgEnum      ArgType = "enum"
+	ArgBool      ArgType = "bool"
+	ArgString    ArgType = "string"
+)
+
+// ArgSpec describes an argument of a command
+type ArgSpec struct {
+	Name   string
+	Type   ArgType
+	Values []string // Allowed values of ArgEnum argument
+}
+
 type CommandResult struct {
 	Messages []string
+	Records  []Record // Structured form of the messages, if the command provides it
 	Err      error
 }
 
+// Named value of a record
+type Field struct {
+	Name  string
+	Value string
+}
+
+// Record is an ordered list of fields, e.g. a window returned by a query
+type Record []Field
+
+// Fields of the record in text form are separated by tabs
+const RECORD_SEPARATOR = "\t"
+
+// Separators inside the values are replaced with spaces,
+// so a record always takes a single line with one value per field
+var recordValueReplacer = strings.NewReplacer(RECORD_SEPARATOR, " ", "\n", " ", "\r", " ")
+
+// String returns values of the fields separated by RECORD_SEPARATOR
+func (r Record) String() string {
+	values := make([]string, len(r))
+	for i, field := range r {
+		values[i] = recordValueReplacer.Replace(field.Value)
+	}
+	return strings.Join(values, RECORD_SEPARATOR)
+}
+
+// MarshalJSON encodes the record as JSON object keeping the order of the fields
+func (r Record) MarshalJSON() ([]byte, error) {
+	buf := new(bytes.Buffer)
+	buf.WriteByte('{')
+	for i, field := range r {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+
+		name, err := json.Marshal(field.Name)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(field.Value)
+		if err != nil {
+			return nil, err
+		}
+
+		buf.Write(name)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+
+	return buf.Bytes(), nil
+}
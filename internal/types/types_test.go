@@ -0,0 +1,82 @@
+package types_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/Alnivel/zentile/internal/types"
+)
+
+func TestRecord_String(t *testing.T) {
+	tests := []struct {
+		name string
+
+		record types.Record
+		want   string
+	}{
+		{
+			name:   "Fields",
+			record: types.Record{{"id", "0x1"}, {"class", "Firefox"}, {"title", "Home"}},
+			want:   "0x1\tFirefox\tHome",
+		},
+		{
+			name:   "SeparatorsInValues",
+			record: types.Record{{"id", "0x1"}, {"title", "a\tb\nc\r\nd"}, {"workspace", "0"}},
+			want:   "0x1\ta b c  d\t0",
+		},
+		{
+			name:   "EmptyValues",
+			record: types.Record{{"id", "0x1"}, {"geometry", ""}, {"role", "floating"}},
+			want:   "0x1\t\tfloating",
+		},
+		{
+			name:   "NoFields",
+			record: types.Record{},
+			want:   "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.record.String(); got != tt.want {
+				t.Errorf("Got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRecord_MarshalJSON(t *testing.T) {
+	tests := []struct {
+		name string
+
+		record types.Record
+		want   string
+	}{
+		{
+			name:   "FieldOrder",
+			record: types.Record{{"workspace", "1"}, {"id", "0x2"}, {"class", "XTerm"}},
+			want:   `{"workspace":"1","id":"0x2","class":"XTerm"}`,
+		},
+		{
+			// Only the text form replaces the separators
+			name:   "SeparatorsInValues",
+			record: types.Record{{"title", "a\tb\nc"}, {"quote", `say "hi"`}},
+			want:   `{"title":"a\tb\nc","quote":"say \"hi\""}`,
+		},
+		{
+			name:   "NoFields",
+			record: types.Record{},
+			want:   `{}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := json.Marshal(tt.record)
+			if err != nil {
+				t.Fatalf("Got error %v, expecting none", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("Got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,258 @@
+// Package client is a Go client of the zentile daemon.
+//
+// It talks to the daemon over its socket, the same way the zentile CLI does:
+//
+//	c, err := client.Dial(ctx, "")
+//	if err != nil {
+//		return err
+//	}
+//	defer c.Close()
+//
+//	err = c.SetLayout(ctx, "vertical")
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/Alnivel/zentile/internal/protocol"
+	"github.com/Alnivel/zentile/internal/socket"
+	"github.com/Alnivel/zentile/internal/types"
+)
+
+// Command sent to the daemon, see COMMANDS.md for the available ones
+type Command = types.Command
+
+type CommandType = types.CommandType
+
+const (
+	Action  = types.Action
+	Set     = types.Set
+	Query   = types.Query
+	For     = types.For
+	Foreach = types.Foreach
+)
+
+// How long to wait for the daemon to reply to the handshake if the context has no deadline
+const handshakeTimeout = time.Second
+
+// Client is a connection to the daemon.
+// It is safe for concurrent use, the commands are sent one at a time.
+type Client struct {
+	sequenceMutex sync.Mutex // Held while a command sequence is sent, so it is not mixed with other commands
+	mutex         sync.Mutex
+	conn          socket.Conn
+	closed        bool
+}
+
+// Result of a command, each message or record of the reply is a JSON value
+type Result struct {
+	Values []json.RawMessage
+}
+
+// Strings returns the messages of the result
+func (r Result) Strings() ([]string, error) {
+	strs := make([]string, len(r.Values))
+	for i, value := range r.Values {
+		if err := json.Unmarshal(value, &strs[i]); err != nil {
+			return nil, err
+		}
+	}
+	return strs, nil
+}
+
+// Decode decodes the records of the result into the slice pointed to by v
+func (r Result) Decode(v any) error {
+	values := r.Values
+	if values == nil {
+		values = []json.RawMessage{}
+	}
+
+	encoded, err := json.Marshal(values)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(encoded, v)
+}
+
+// Dial connects to the daemon listening on the socket path,
+// or on the default one of the current display if the path is empty.
+// The context limits the connection and the handshake, not the lifetime of the client.
+func Dial(ctx context.Context, path string) (*Client, error) {
+	return DialWithLimit(ctx, path, socket.DEFAULT_MAX_MESSAGE_LENGTH)
+}
+
+// DialWithLimit is like Dial, but the replies of the daemon can be up to maxMessageLength bytes long
+// instead of 4 MiB, e.g. to query windows of a very busy desktop
+func DialWithLimit(ctx context.Context, path string, maxMessageLength int) (*Client, error) {
+	conn, err := dial(ctx, path, maxMessageLength)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Client{conn: conn}
+	if _, err := c.roundTrip(ctx, "FORMAT", "json"); err != nil {
+		c.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// Connect to the daemon and check that it speaks the same protocol version
+func dial(ctx context.Context, path string, maxMessageLength int) (socket.Conn, error) {
+	if path == "" {
+		path = socket.DefaultPath()
+	}
+
+	var dialer net.Dialer
+	netConn, err := dialer.DialContext(ctx, "unix", path)
+	if err != nil {
+		return socket.Conn{}, err
+	}
+
+	conn := socket.NewSocketConnWithLimit(netConn, maxMessageLength)
+	if err := handshake(ctx, &conn); err != nil {
+		conn.Close()
+		return socket.Conn{}, err
+	}
+	return conn, nil
+}
+
+// Exchange HELLO messages with the daemon within the deadline of the context.
+// Daemons older than the handshake do not reply to it, so without the deadline the reply is awaited only for a while.
+func handshake(ctx context.Context, conn *socket.Conn) error {
+	deadline, hasDeadline := ctx.Deadline()
+	if !hasDeadline {
+		deadline = time.Now().Add(handshakeTimeout)
+	}
+	conn.SetReadDeadline(deadline)
+	defer conn.SetReadDeadline(time.Time{})
+	stop := context.AfterFunc(ctx, func() {
+		conn.SetReadDeadline(time.Now())
+	})
+	defer stop()
+
+	err := protocol.Handshake(conn)
+	if err != nil && (hasDeadline || ctx.Err() != nil) {
+		return contextErr(ctx, err)
+	}
+	return err
+}
+
+// Close closes the connection to the daemon
+func (c *Client) Close() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.close()
+}
+
+func (c *Client) close() {
+	if !c.closed {
+		c.closed = true
+		c.conn.Close()
+	}
+}
+
+// Do runs the command as a command sequence of its own
+func (c *Client) Do(ctx context.Context, command Command) (Result, error) {
+	c.sequenceMutex.Lock()
+	defer c.sequenceMutex.Unlock()
+
+	return c.do(ctx, command)
+}
+
+func (c *Client) do(ctx context.Context, command Command) (Result, error) {
+	return c.roundTrip(ctx, string(command.Kind), append([]string{command.Name}, command.Args...)...)
+}
+
+// Sequence runs the commands as one command sequence: the windows are rearranged once
+// at the end of it, and the layout changes are rolled back if any of the commands fails.
+// The results of the commands run before the first error are returned along with it.
+func (c *Client) Sequence(ctx context.Context, commands ...Command) ([]Result, error) {
+	c.sequenceMutex.Lock()
+	defer c.sequenceMutex.Unlock()
+
+	if _, err := c.roundTrip(ctx, "BEGIN"); err != nil {
+		return nil, err
+	}
+
+	results := make([]Result, 0, len(commands))
+	var commandErr error
+	for _, command := range commands {
+		result, err := c.do(ctx, command)
+		if err != nil {
+			commandErr = err
+			break
+		}
+		results = append(results, result)
+	}
+
+	_, endErr := c.roundTrip(ctx, "END")
+	if commandErr != nil {
+		return results, commandErr
+	}
+	return results, endErr
+}
+
+// Send the message and wait for the reply.
+// If the context is done first, the connection is closed, as the reply would be out of order.
+func (c *Client) roundTrip(ctx context.Context, kind string, args ...string) (Result, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.closed {
+		return Result{}, Closed
+	}
+	if err := ctx.Err(); err != nil {
+		return Result{}, err
+	}
+
+	deadline, _ := ctx.Deadline()
+	c.conn.SetReadDeadline(deadline)
+	stop := context.AfterFunc(ctx, func() {
+		c.conn.SetReadDeadline(time.Now())
+	})
+	defer stop()
+
+	err := c.conn.Send(kind, args...)
+	var reply socket.Message
+	if err == nil {
+		reply, err = c.conn.Receive()
+	}
+	if err != nil {
+		c.close()
+		return Result{}, contextErr(ctx, err)
+	}
+
+	switch reply.Kind {
+	case "OK":
+		values := make([]json.RawMessage, len(reply.Args))
+		for i, arg := range reply.Args {
+			values[i] = json.RawMessage(arg)
+		}
+		return Result{Values: values}, nil
+	case "ERR":
+		return Result{}, replyError(reply)
+	default:
+		return Result{}, fmt.Errorf("Unexpected reply %v", reply)
+	}
+}
+
+// Error of the context if the connection failed because the context is done
+func contextErr(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	// The deadline of the connection may pass slightly before the one of the context
+	if _, hasDeadline := ctx.Deadline(); hasDeadline && errors.Is(err, os.ErrDeadlineExceeded) {
+		return context.DeadlineExceeded
+	}
+	return err
+}
@@ -0,0 +1,228 @@
+package client_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/Alnivel/zentile/internal/protocol"
+	"github.com/Alnivel/zentile/internal/socket"
+	"github.com/Alnivel/zentile/pkg/client"
+)
+
+// Reply of the fake daemon to the message, no reply is sent if ok is false
+type replyFunc func(message socket.Message) (reply socket.Message, ok bool)
+
+// Start a fake daemon replying to the messages after the handshake, it returns the socket path
+func fakeDaemon(t *testing.T, version int, reply replyFunc) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "zentile.sock")
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			netConn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+
+			go func() {
+				conn := socket.NewSocketConn(netConn)
+				defer conn.Close()
+
+				for {
+					message, err := conn.Receive()
+					if err != nil {
+						return
+					}
+
+					if message.Kind == "HELLO" {
+						conn.Send("HELLO", strconv.Itoa(version))
+						continue
+					}
+					if r, ok := reply(message); ok {
+						conn.SendMessage(r)
+					}
+				}
+			}()
+		}
+	}()
+
+	return path
+}
+
+func okReply(message socket.Message) (socket.Message, bool) {
+	return socket.Message{Kind: "OK"}, true
+}
+
+func TestClient_QueryWindows(t *testing.T) {
+	path := fakeDaemon(t, protocol.VERSION, func(message socket.Message) (socket.Message, bool) {
+		if message.Kind == "QUERY" && message.Args[0] == "windows" {
+			return socket.Message{Kind: "OK", Args: []string{
+				`{"id":"0x1","class":"Firefox","title":"Home","workspace":"0","role":"master","geometry":"800x600+0+0"}`,
+				`{"id":"0x2","class":"XTerm","title":"sh","workspace":"1","role":"floating","geometry":""}`,
+			}}, true
+		}
+		return okReply(message)
+	})
+
+	c, err := client.Dial(context.Background(), path)
+	if err != nil {
+		t.Fatalf("Got dial error %v, expecting none", err)
+	}
+	defer c.Close()
+
+	got, err := c.QueryWindows(context.Background())
+	if err != nil {
+		t.Fatalf("Got error %v, expecting none", err)
+	}
+
+	want := []client.Window{
+		{Id: "0x1", Class: "Firefox", Title: "Home", Workspace: 0, Role: "master", Geometry: "800x600+0+0"},
+		{Id: "0x2", Class: "XTerm", Title: "sh", Workspace: 1, Role: "floating", Geometry: ""},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("QueryWindows() = %v, want %v", got, want)
+	}
+}
+
+func TestClient_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+
+		reply    socket.Message
+		wantErr  error
+		wantCode client.ErrorCode
+	}{
+		{"WindowNotFound", socket.Message{Kind: "ERR", Args: []string{"window_not_found", "Mark do not exists"}}, client.WindowNotFound, client.CodeWindowNotFound},
+		{"NotTiling", socket.Message{Kind: "ERR", Args: []string{"not_tiling", "Target workspace is not tiling"}}, client.NotTiling, client.CodeNotTiling},
+		{"NoCode", socket.Message{Kind: "ERR", Args: []string{"Something failed"}}, nil, client.CodeFailed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := fakeDaemon(t, protocol.VERSION, func(message socket.Message) (socket.Message, bool) {
+				if message.Kind == "ACTION" {
+					return tt.reply, true
+				}
+				return okReply(message)
+			})
+
+			c, err := client.Dial(context.Background(), path)
+			if err != nil {
+				t.Fatalf("Got dial error %v, expecting none", err)
+			}
+			defer c.Close()
+
+			err = c.Swap(context.Background(), "%a", "")
+
+			var commandErr *client.CommandError
+			if !errors.As(err, &commandErr) {
+				t.Fatalf("Got error %v, expecting CommandError", err)
+			}
+			if commandErr.Code != tt.wantCode {
+				t.Errorf("Got code %v, expecting %v", commandErr.Code, tt.wantCode)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("Got error %v, expecting %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestClient_ContextDeadline(t *testing.T) {
+	path := fakeDaemon(t, protocol.VERSION, func(message socket.Message) (socket.Message, bool) {
+		// The daemon is stuck on the command
+		if message.Kind == "ACTION" {
+			return socket.Message{}, false
+		}
+		return okReply(message)
+	})
+
+	c, err := client.Dial(context.Background(), path)
+	if err != nil {
+		t.Fatalf("Got dial error %v, expecting none", err)
+	}
+	defer c.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	if err := c.Tile(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Got error %v, expecting %v", err, context.DeadlineExceeded)
+	}
+	if err := c.Tile(context.Background()); !errors.Is(err, client.Closed) {
+		t.Errorf("Got error %v after the deadline, expecting %v", err, client.Closed)
+	}
+}
+
+func TestDial_IncompatibleDaemon(t *testing.T) {
+	path := fakeDaemon(t, protocol.VERSION-1, okReply)
+
+	_, err := client.Dial(context.Background(), path)
+	if !errors.Is(err, client.IncompatibleDaemon) {
+		t.Errorf("Got error %v, expecting %v", err, client.IncompatibleDaemon)
+	}
+}
+
+func TestSubscribe(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "zentile.sock")
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer listener.Close()
+
+	go func() {
+		netConn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		conn := socket.NewSocketConn(netConn)
+		defer conn.Close()
+
+		conn.Receive()
+		conn.Send("HELLO", strconv.Itoa(protocol.VERSION))
+		conn.Receive()
+		conn.Send("OK")
+		conn.Send("EVENT", "layout_changed", "0", "vertical")
+		conn.Send("EVENT", "window_focused", "0x1")
+
+		// Wait for the client to close the connection
+		conn.Receive()
+	}()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	subscription, err := client.Subscribe(ctx, path, client.LayoutChanged, client.WindowFocused)
+	if err != nil {
+		t.Fatalf("Got subscribe error %v, expecting none", err)
+	}
+
+	want := []client.Event{
+		{Name: client.LayoutChanged, Args: []string{"0", "vertical"}},
+		{Name: client.WindowFocused, Args: []string{"0x1"}},
+	}
+	for _, wantEvent := range want {
+		if got := <-subscription.Events(); !reflect.DeepEqual(got, wantEvent) {
+			t.Errorf("Got event %v, expecting %v", got, wantEvent)
+		}
+	}
+
+	cancel()
+	for range subscription.Events() {
+	}
+	if err := subscription.Err(); !errors.Is(err, context.Canceled) {
+		t.Errorf("Got error %v after cancel, expecting %v", err, context.Canceled)
+	}
+}
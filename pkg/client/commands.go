@@ -0,0 +1,266 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+)
+
+// Window as printed by the windows query
+type Window struct {
+	Id        string `json:"id"`
+	Class     string `json:"class"`
+	Title     string `json:"title"`
+	Workspace uint   `json:"workspace,string"`
+	Role      string `json:"role"`     // master, slave or floating
+	Geometry  string `json:"geometry"` // WIDTHxHEIGHT+X+Y, empty if unknown
+}
+
+// Workspace as printed by the workspaces query
+type Workspace struct {
+	Num         uint    `json:"workspace,string"`
+	Name        string  `json:"name"`
+	Tiling      bool    `json:"tiling,string"`
+	Layout      string  `json:"layout"`
+	MasterCount int     `json:"master_count,string"`
+	Proportion  float64 `json:"proportion,string"`
+	Gap         int     `json:"gap,string"`
+}
+
+// Mark as printed by the marks query
+type Mark struct {
+	Name     string `json:"name"`
+	WindowId string `json:"id"`
+}
+
+// Command description as printed by the commands query
+type CommandInfo struct {
+	Kind        string `json:"kind"`
+	Name        string `json:"name"`
+	MinArgs     int    `json:"min_args,string"`
+	MaxArgs     int    `json:"max_args,string"`
+	Usage       string `json:"usage"`
+	Description string `json:"description"`
+}
+
+// ForWindow sets the target window for the rest of the command sequence, see Sequence.
+// WID is a window id, a mark or a selector, e.g. "%master".
+func ForWindow(wid string) Command {
+	return Command{Kind: For, Name: "window", Args: []string{wid}}
+}
+
+// ForWorkspace sets the target workspace for the rest of the command sequence, see Sequence.
+// The workspace is a number, a name or one of current, next, prev and last.
+func ForWorkspace(workspace string) Command {
+	return Command{Kind: For, Name: "workspace", Args: []string{workspace}}
+}
+
+// Enable tiling for the current workspace
+func (c *Client) Tile(ctx context.Context) error {
+	return c.action(ctx, "tile")
+}
+
+// Disable tiling for the current workspace
+func (c *Client) Untile(ctx context.Context) error {
+	return c.action(ctx, "untile")
+}
+
+// Make the active window master
+func (c *Client) MakeActiveWindowMaster(ctx context.Context) error {
+	return c.action(ctx, "make_active_window_master")
+}
+
+// Cycle through layouts of the current workspace
+func (c *Client) SwitchLayout(ctx context.Context) error {
+	return c.action(ctx, "switch_layout")
+}
+
+// Increase number of windows in master column/row
+func (c *Client) IncreaseMaster(ctx context.Context) error {
+	return c.action(ctx, "increase_master")
+}
+
+// Decrease number of windows in master column/row
+func (c *Client) DecreaseMaster(ctx context.Context) error {
+	return c.action(ctx, "decrease_master")
+}
+
+// Grow width/height of master column/row
+func (c *Client) IncrementMaster(ctx context.Context) error {
+	return c.action(ctx, "increment_master")
+}
+
+// Shrink width/height of master column/row
+func (c *Client) DecrementMaster(ctx context.Context) error {
+	return c.action(ctx, "decrement_master")
+}
+
+// Focus the window OFFSET windows after the active one
+func (c *Client) NextWindow(ctx context.Context, offset int) error {
+	return c.action(ctx, "next_window", strconv.Itoa(offset))
+}
+
+// Focus the window OFFSET windows before the active one
+func (c *Client) PreviousWindow(ctx context.Context, offset int) error {
+	return c.action(ctx, "previous_window", strconv.Itoa(offset))
+}
+
+// Swap locations of the windows, the active window is swapped with WID_A if WID_B is empty
+func (c *Client) Swap(ctx context.Context, widA, widB string) error {
+	if widB == "" {
+		return c.action(ctx, "swap", widA)
+	}
+	return c.action(ctx, "swap", widA, widB)
+}
+
+// Mark the window WID, or the active one if WID is empty, with the name
+func (c *Client) Mark(ctx context.Context, name, wid string) error {
+	if wid == "" {
+		return c.action(ctx, "mark", name)
+	}
+	return c.action(ctx, "mark", name, wid)
+}
+
+// Remove the mark
+func (c *Client) Unmark(ctx context.Context, name string) error {
+	return c.action(ctx, "unmark", name)
+}
+
+// Set layout of the current workspace, "none" untiles it
+func (c *Client) SetLayout(ctx context.Context, name string) error {
+	return c.set(ctx, "layout", name)
+}
+
+// Set layouts of the current workspace cycled through by SwitchLayout
+func (c *Client) SetLayouts(ctx context.Context, names ...string) error {
+	return c.set(ctx, "layouts", names...)
+}
+
+// Set gap between the windows of the current workspace
+func (c *Client) SetGap(ctx context.Context, gap int) error {
+	return c.set(ctx, "gap", strconv.Itoa(gap))
+}
+
+// Set proportion of the master area of the current workspace
+func (c *Client) SetProportion(ctx context.Context, proportion float64) error {
+	return c.set(ctx, "proportion", strconv.FormatFloat(proportion, 'f', -1, 64))
+}
+
+// Set number of master windows of the current workspace
+func (c *Client) SetMasterCount(ctx context.Context, count int) error {
+	return c.set(ctx, "master_count", strconv.Itoa(count))
+}
+
+// Set whether decorations of the windows in the current workspace are removed while tiling
+func (c *Client) SetRemoveDecorations(ctx context.Context, remove bool) error {
+	return c.set(ctx, "remove_decorations", switchValue(remove))
+}
+
+// Set whether the current workspace is tiling when it is created
+func (c *Client) SetStartTiling(ctx context.Context, tiling bool) error {
+	return c.set(ctx, "start_tiling", switchValue(tiling))
+}
+
+// Layout of the current workspace, "none" if it is not tiling
+func (c *Client) QueryLayout(ctx context.Context) (string, error) {
+	return c.queryString(ctx, "layout")
+}
+
+// All tracked windows
+func (c *Client) QueryWindows(ctx context.Context) ([]Window, error) {
+	var windows []Window
+	err := c.queryRecords(ctx, &windows, "windows")
+	return windows, err
+}
+
+// All workspaces
+func (c *Client) QueryWorkspaces(ctx context.Context) ([]Workspace, error) {
+	var workspaces []Workspace
+	err := c.queryRecords(ctx, &workspaces, "workspaces")
+	return workspaces, err
+}
+
+// ID of the active window
+func (c *Client) QueryActiveWindow(ctx context.Context) (string, error) {
+	return c.queryString(ctx, "active_window")
+}
+
+// Number of master windows of the current workspace
+func (c *Client) QueryMasterCount(ctx context.Context) (int, error) {
+	value, err := c.queryString(ctx, "master_count")
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(value)
+}
+
+// Proportion of the master area of the current workspace
+func (c *Client) QueryProportion(ctx context.Context) (float64, error) {
+	value, err := c.queryString(ctx, "proportion")
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseFloat(value, 64)
+}
+
+// All marks
+func (c *Client) QueryMarks(ctx context.Context) ([]Mark, error) {
+	var marks []Mark
+	err := c.queryRecords(ctx, &marks, "marks")
+	return marks, err
+}
+
+// The window OFFSET windows after the active one, negative offset counts backwards
+func (c *Client) QueryNextWindow(ctx context.Context, offset int) (string, error) {
+	return c.queryString(ctx, "next_window", strconv.Itoa(offset))
+}
+
+// All commands of the daemon, including the aliases
+func (c *Client) QueryCommands(ctx context.Context) ([]CommandInfo, error) {
+	var commands []CommandInfo
+	err := c.queryRecords(ctx, &commands, "commands")
+	return commands, err
+}
+
+func (c *Client) action(ctx context.Context, name string, args ...string) error {
+	_, err := c.Do(ctx, Command{Kind: Action, Name: name, Args: args})
+	return err
+}
+
+func (c *Client) set(ctx context.Context, name string, args ...string) error {
+	_, err := c.Do(ctx, Command{Kind: Set, Name: name, Args: args})
+	return err
+}
+
+// The only message printed by the query
+func (c *Client) queryString(ctx context.Context, name string, args ...string) (string, error) {
+	result, err := c.Do(ctx, Command{Kind: Query, Name: name, Args: args})
+	if err != nil {
+		return "", err
+	}
+
+	strs, err := result.Strings()
+	if err != nil {
+		return "", err
+	}
+	if len(strs) != 1 {
+		return "", fmt.Errorf("Unexpected result of query %v: %v", name, strs)
+	}
+	return strs[0], nil
+}
+
+// Decode the records printed by the query into the slice pointed to by v
+func (c *Client) queryRecords(ctx context.Context, v any, name string, args ...string) error {
+	result, err := c.Do(ctx, Command{Kind: Query, Name: name, Args: args})
+	if err != nil {
+		return err
+	}
+	return result.Decode(v)
+}
+
+func switchValue(on bool) string {
+	if on {
+		return "on"
+	}
+	return "off"
+}
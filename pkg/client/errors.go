@@ -0,0 +1,60 @@
+package client
+
+import (
+	"errors"
+
+	"github.com/Alnivel/zentile/internal/protocol"
+	"github.com/Alnivel/zentile/internal/socket"
+)
+
+var (
+	IncompatibleDaemon = protocol.IncompatibleDaemon
+	Closed             = errors.New("Client is closed")
+)
+
+// Categories of the errors reported by the daemon, to be checked with errors.Is
+var (
+	UnknownCommand      = protocol.UnknownCommand
+	BadArguments        = protocol.BadArguments
+	WindowNotFound      = protocol.WindowNotFound
+	WorkspaceOutOfRange = protocol.WorkspaceOutOfRange
+	NotTiling           = protocol.NotTiling
+	BackendFailure      = protocol.BackendFailure
+	SequenceAborted     = protocol.SequenceAborted
+)
+
+// Stable code of the error reported by the daemon, see the Errors section of COMMANDS.md
+type ErrorCode = protocol.ErrorCode
+
+const (
+	CodeUnknownCommand      = protocol.CodeUnknownCommand
+	CodeBadArguments        = protocol.CodeBadArguments
+	CodeWindowNotFound      = protocol.CodeWindowNotFound
+	CodeWorkspaceOutOfRange = protocol.CodeWorkspaceOutOfRange
+	CodeNotTiling           = protocol.CodeNotTiling
+	CodeBackendFailure      = protocol.CodeBackendFailure
+	CodeAborted             = protocol.CodeAborted
+	CodeUnknownMessage      = protocol.CodeUnknownMessage
+	CodeFailed              = protocol.CodeFailed
+)
+
+// CommandError is an error reported by the daemon in ERR reply
+type CommandError struct {
+	Code ErrorCode
+	Text string
+}
+
+func (e *CommandError) Error() string {
+	return e.Text
+}
+
+// Unwrap returns the error category of the code, so the error can be checked with errors.Is
+func (e *CommandError) Unwrap() error {
+	return protocol.ErrorOfCode(e.Code)
+}
+
+// Error of ERR reply
+func replyError(reply socket.Message) error {
+	code, text := protocol.ReplyError(reply)
+	return &CommandError{Code: code, Text: text}
+}
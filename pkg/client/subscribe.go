@@ -0,0 +1,158 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/Alnivel/zentile/internal/protocol"
+	"github.com/Alnivel/zentile/internal/socket"
+)
+
+type EventName = protocol.EventName
+
+const (
+	WorkspaceChanged = protocol.WorkspaceChanged
+	LayoutChanged    = protocol.LayoutChanged
+	TilingToggled    = protocol.TilingToggled
+	WindowAdded      = protocol.WindowAdded
+	WindowRemoved    = protocol.WindowRemoved
+	WindowFocused    = protocol.WindowFocused
+	MasterChanged    = protocol.MasterChanged
+)
+
+// Event sent by the daemon, see the Events section of COMMANDS.md for the arguments
+type Event struct {
+	Name EventName
+	Args []string
+}
+
+// Subscription delivers the events until it is closed, its context is done
+// or the daemon closes the connection
+type Subscription struct {
+	conn      socket.Conn
+	events    chan Event
+	err       error
+	done      chan struct{} // Closed by Close
+	closeOnce sync.Once
+}
+
+// Subscribe connects to the daemon listening on the socket path, or on the default one if the path is empty,
+// and subscribes to the events with the names, or to all events if none provided.
+// The subscription lasts until the context is done or it is closed.
+func Subscribe(ctx context.Context, path string, names ...EventName) (*Subscription, error) {
+	conn, err := dial(ctx, path, socket.DEFAULT_MAX_MESSAGE_LENGTH)
+	if err != nil {
+		return nil, err
+	}
+
+	args := make([]string, len(names))
+	for i, name := range names {
+		args[i] = string(name)
+	}
+
+	reply, err := subscribe(ctx, &conn, args)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if reply.Kind != "OK" {
+		conn.Close()
+		return nil, replyError(reply)
+	}
+
+	s := &Subscription{
+		conn:   conn,
+		events: make(chan Event),
+		done:   make(chan struct{}),
+	}
+	go s.receive(ctx)
+
+	return s, nil
+}
+
+// Send SUBSCRIBE message and wait for the reply within the deadline of the context
+func subscribe(ctx context.Context, conn *socket.Conn, args []string) (socket.Message, error) {
+	if err := conn.Send("SUBSCRIBE", args...); err != nil {
+		return socket.Message{}, err
+	}
+
+	deadline, _ := ctx.Deadline()
+	conn.SetReadDeadline(deadline)
+	defer conn.SetReadDeadline(time.Time{})
+	stop := context.AfterFunc(ctx, func() {
+		conn.SetReadDeadline(time.Now())
+	})
+	defer stop()
+
+	reply, err := conn.Receive()
+	if err != nil {
+		return reply, contextErr(ctx, err)
+	}
+	return reply, nil
+}
+
+// Events returns the channel of the events, it is closed when the subscription ends
+func (s *Subscription) Events() <-chan Event {
+	return s.events
+}
+
+// Err returns the reason the subscription ended: the error of the context if it is done,
+// nil if the subscription was closed or the daemon closed the connection.
+// It is valid after the events channel is closed.
+func (s *Subscription) Err() error {
+	return s.err
+}
+
+// Close ends the subscription
+func (s *Subscription) Close() {
+	s.closeOnce.Do(func() {
+		close(s.done)
+		// Interrupt the pending read
+		s.conn.SetReadDeadline(time.Now())
+	})
+}
+
+func (s *Subscription) receive(ctx context.Context) {
+	defer close(s.events)
+	defer s.conn.Close()
+
+	stop := context.AfterFunc(ctx, s.Close)
+	defer stop()
+
+	for {
+		message, err := s.conn.Receive()
+		if err != nil {
+			s.err = s.endErr(ctx, err)
+			return
+		}
+
+		if message.Kind != "EVENT" || len(message.Args) == 0 {
+			continue
+		}
+		event := Event{Name: EventName(message.Args[0]), Args: message.Args[1:]}
+
+		select {
+		case s.events <- event:
+		case <-s.done:
+			s.err = ctx.Err()
+			return
+		}
+	}
+}
+
+// Reason the subscription ended after the receive error
+func (s *Subscription) endErr(ctx context.Context, err error) error {
+	select {
+	case <-s.done:
+		return ctx.Err()
+	default:
+	}
+
+	if errors.Is(err, io.EOF) {
+		return nil
+	}
+	return err
+}